@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Permission bits requested when simulating access.
+const (
+	permRead    = 04
+	permWrite   = 02
+	permExecute = 01
+)
+
+// Tags and layout of a POSIX ACL stored in the system.posix_acl_access
+// extended attribute (linux/posix_acl_xattr.h). All fields are little-endian.
+const (
+	aclVersion   = 0x0002
+	aclHeader    = 4
+	aclEntrySize = 8
+
+	aclUserObj  = 0x01
+	aclUser     = 0x02
+	aclGroupObj = 0x04
+	aclGroup    = 0x08
+	aclMask     = 0x10
+	aclOther    = 0x20
+)
+
+// errInvalidACL indicates a malformed system.posix_acl_access attribute.
+var errInvalidACL = errors.New("invalid ACL data")
+
+// identity describes the credentials whose access is simulated with --as.
+type identity struct {
+	Name   string
+	Uid    int
+	Gid    int
+	Groups []int
+}
+
+// access records the outcome of simulating access to an entry.
+type access struct {
+	Perm   string `json:"perm"`
+	Need   string `json:"need"`
+	Denied bool   `json:"denied"`
+	Reason string `json:"reason,omitempty"`
+
+	// first marks the entry at which resolution would first be denied.
+	first bool
+}
+
+// aclEntry is a single entry of a POSIX access ACL.
+type aclEntry struct {
+	Tag  uint16
+	Perm uint16
+	ID   uint32
+}
+
+// lookupIdentity resolves a USER[:GROUP] specification, given by name or
+// numeric ID, along with the user's supplementary groups from NSS.
+func lookupIdentity(spec string) (id identity, err error) {
+	name, group, hasGroup := strings.Cut(spec, ":")
+	if name == "" {
+		return id, fmt.Errorf("invalid identity: %q", spec)
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		var e error
+		if u, e = user.LookupId(name); e != nil {
+			return id, err
+		}
+	}
+
+	id.Name = u.Username
+	if id.Uid, err = strconv.Atoi(u.Uid); err != nil {
+		return id, fmt.Errorf("user %s: invalid uid: %s", u.Username, u.Uid)
+	}
+	if id.Gid, err = strconv.Atoi(u.Gid); err != nil {
+		return id, fmt.Errorf("user %s: invalid gid: %s", u.Username, u.Gid)
+	}
+
+	if gids, e := u.GroupIds(); e == nil {
+		for _, g := range gids {
+			if n, e := strconv.Atoi(g); e == nil {
+				id.Groups = append(id.Groups, n)
+			}
+		}
+	}
+
+	if hasGroup {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			var e error
+			if g, e = user.LookupGroupId(group); e != nil {
+				return id, err
+			}
+		}
+		if id.Gid, err = strconv.Atoi(g.Gid); err != nil {
+			return id, fmt.Errorf("group %s: invalid gid: %s", g.Name, g.Gid)
+		}
+	}
+
+	return id, nil
+}
+
+// currentIdentity returns the effective credentials of this process.
+func currentIdentity() identity {
+	id := identity{Uid: os.Geteuid(), Gid: os.Getegid()}
+	id.Groups, _ = os.Getgroups()
+	id.Name = strconv.Itoa(id.Uid)
+	if u, err := user.LookupId(id.Name); err == nil {
+		id.Name = u.Username
+	}
+	return id
+}
+
+// String returns the identity name with its primary group ID.
+func (id identity) String() string {
+	return fmt.Sprintf("%s(%d:%d)", id.Name, id.Uid, id.Gid)
+}
+
+// inGroup reports whether the identity is a member of the given group.
+func (id identity) inGroup(gid int) bool {
+	if gid == id.Gid {
+		return true
+	}
+	for _, g := range id.Groups {
+		if g == gid {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeACL parses the raw system.posix_acl_access attribute value.
+func decodeACL(data []byte) ([]aclEntry, error) {
+	if len(data) < aclHeader || (len(data)-aclHeader)%aclEntrySize != 0 ||
+		binary.LittleEndian.Uint32(data) != aclVersion {
+		return nil, errInvalidACL
+	}
+
+	var acl []aclEntry
+	for off := aclHeader; off < len(data); off += aclEntrySize {
+		acl = append(acl, aclEntry{
+			Tag:  binary.LittleEndian.Uint16(data[off:]),
+			Perm: binary.LittleEndian.Uint16(data[off+2:]),
+			ID:   binary.LittleEndian.Uint32(data[off+4:]),
+		})
+	}
+	return acl, nil
+}
+
+// entryACL returns the POSIX access ACL of the entry, or nil if it has none.
+func entryACL(e *entry) []aclEntry {
+	data, err := getACL(e.Dest)
+	if err != nil || data == nil {
+		return nil
+	}
+	acl, _ := decodeACL(data)
+	return acl
+}
+
+// effectivePerm returns the rwx bits the identity is granted on the entry and
+// the ACL class (or mode class) that granted them, following the POSIX ACL
+// access check algorithm. Without an ACL, only the mode bits are considered.
+func effectivePerm(e *entry, id identity, acl []aclEntry) (perm uint32, class string) {
+	m := uint32(e.Info.Mode().Perm())
+
+	// The superuser bypasses read and write checks, and search checks on
+	// directories, but can only execute files with at least one x bit.
+	if id.Uid == 0 {
+		perm = permRead | permWrite
+		if e.Info.IsDir() || 0 != m&0111 {
+			perm |= permExecute
+		}
+		return perm, "root"
+	}
+
+	if id.Uid == e.Uid {
+		return (m >> 6) & 07, "owner"
+	}
+
+	if len(acl) == 0 {
+		if id.inGroup(e.Gid) {
+			return (m >> 3) & 07, "group"
+		}
+		return m & 07, "other"
+	}
+
+	mask := uint32(07)
+	for _, a := range acl {
+		if a.Tag == aclMask {
+			mask = uint32(a.Perm) & 07
+		}
+	}
+
+	for _, a := range acl {
+		if a.Tag == aclUser && int(a.ID) == id.Uid {
+			return uint32(a.Perm) & mask, "user:" + id.Name
+		}
+	}
+
+	// A process matching several group entries is granted the union of
+	// them, but is never considered against the "other" entry.
+	var matched bool
+	for _, a := range acl {
+		switch {
+		case a.Tag == aclGroupObj && id.inGroup(e.Gid):
+			class = "group"
+		case a.Tag == aclGroup && id.inGroup(int(a.ID)):
+			class = "group:" + strconv.FormatUint(uint64(a.ID), 10)
+		default:
+			continue
+		}
+		matched = true
+		perm |= uint32(a.Perm) & mask
+	}
+	if matched {
+		return perm, class
+	}
+
+	for _, a := range acl {
+		if a.Tag == aclOther {
+			return uint32(a.Perm) & 07, "other"
+		}
+	}
+	return m & 07, "other"
+}
+
+// fmtPerm returns an rwx triple for the given permission bits.
+func fmtPerm(perm uint32) string {
+	s := []byte("rwx")
+	for i := range s {
+		if 0 == perm&(04>>i) {
+			s[i] = '-'
+		}
+	}
+	return string(s)
+}
+
+// evalAccess simulates resolving the walked entries as the given identity,
+// recording on each entry the access it requires and whether it is granted.
+// Directories must be searchable, links must be followable under the
+// protected_symlinks rules, and the endpoint must be readable or executable.
+func evalAccess(entries []entry, id identity) {
+	for i := range entries {
+		e := &entries[i]
+		if e.Err != nil || e.Info == nil {
+			continue
+		}
+
+		perm, class := effectivePerm(e, id, entryACL(e))
+		a := &access{Perm: fmtPerm(perm)}
+		held := fmt.Sprintf("%s as %s: %s", id.Name, class, a.Perm)
+
+		switch {
+		case e.Link != "":
+			a.Need = "follow"
+			if reason, ok := mayFollowLink(e, id); !ok {
+				a.Denied, a.Reason = true, reason
+			}
+		case i+1 < len(entries):
+			a.Need = "search"
+			if 0 == perm&permExecute {
+				a.Denied, a.Reason = true, "no search permission ("+held+")"
+			}
+		case e.Info.IsDir():
+			a.Need = "read,search"
+			if 0 == perm&(permRead|permExecute) {
+				a.Denied, a.Reason = true, "cannot list or search ("+held+")"
+			}
+		default:
+			a.Need = "read,execute"
+			if 0 == perm&(permRead|permExecute) {
+				a.Denied, a.Reason = true, "cannot read or execute ("+held+")"
+			}
+		}
+
+		e.Access = a
+	}
+
+	if i := firstDenied(entries); i >= 0 {
+		entries[i].Access.first = true
+	}
+}
+
+// mayFollowLink applies the fs.protected_symlinks rule: in a sticky
+// world-writable directory, a symlink may only be followed by its owner, or
+// if the directory and the symlink share an owner.
+func mayFollowLink(e *entry, id identity) (reason string, ok bool) {
+	if !protectedSymlinks() {
+		return "", true
+	}
+
+	dir, err := os.Stat(filepath.Dir(e.Dest))
+	if err != nil {
+		return "", true
+	}
+	m := dir.Mode()
+	if 0 == m&fs.ModeSticky || 0 == m.Perm()&0002 {
+		return "", true
+	}
+
+	_, _, duid, _, _ := getOwnerInfo(dir)
+	if id.Uid == e.Uid || duid == e.Uid {
+		return "", true
+	}
+	return fmt.Sprintf("protected_symlinks: link owned by %s in sticky world-writable directory, followed by %s",
+		e.User, id.Name), false
+}
+
+// firstDenied returns the index of the first entry denied access, or -1.
+func firstDenied(entries []entry) int {
+	for i, e := range entries {
+		if e.Access != nil && e.Access.Denied {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+// aclData builds a raw system.posix_acl_access attribute value.
+func aclData(acl ...aclEntry) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, aclVersion)
+	for _, a := range acl {
+		b = binary.LittleEndian.AppendUint16(b, a.Tag)
+		b = binary.LittleEndian.AppendUint16(b, a.Perm)
+		b = binary.LittleEndian.AppendUint32(b, a.ID)
+	}
+	return b
+}
+
+// TestDecodeACL tests parsing of POSIX access ACLs.
+func TestDecodeACL(t *testing.T) {
+	want := []aclEntry{
+		{Tag: aclUserObj, Perm: 07},
+		{Tag: aclUser, Perm: 05, ID: 1000},
+		{Tag: aclGroupObj, Perm: 05},
+		{Tag: aclMask, Perm: 05},
+		{Tag: aclOther, Perm: 0},
+	}
+
+	got, err := decodeACL(aclData(want...))
+	if err != nil {
+		t.Fatalf("decodeACL() error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("decodeACL() returned %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("decodeACL()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	for _, bad := range [][]byte{
+		nil,
+		{0x01, 0x00, 0x00, 0x00},
+		aclData(want...)[:10],
+	} {
+		if _, err := decodeACL(bad); err == nil {
+			t.Errorf("decodeACL(%x) error = nil, want error", bad)
+		}
+	}
+}
+
+// TestEffectivePerm tests permission evaluation with mode bits and ACLs.
+func TestEffectivePerm(t *testing.T) {
+	alice := identity{Name: "alice", Uid: 1000, Gid: 1000, Groups: []int{1000, 27}}
+
+	file := func(mode os.FileMode, uid, gid int) *entry {
+		return &entry{Uid: uid, Gid: gid, Info: mockFileInfo{mode: mode}}
+	}
+
+	tests := []struct {
+		name      string
+		e         *entry
+		id        identity
+		acl       []aclEntry
+		wantPerm  string
+		wantClass string
+	}{
+		{
+			name:      "owner",
+			e:         file(0750, 1000, 0),
+			id:        alice,
+			wantPerm:  "rwx",
+			wantClass: "owner",
+		},
+		{
+			name:      "supplementary group",
+			e:         file(0750, 0, 27),
+			id:        alice,
+			wantPerm:  "r-x",
+			wantClass: "group",
+		},
+		{
+			name:      "other",
+			e:         file(0751, 0, 0),
+			id:        alice,
+			wantPerm:  "--x",
+			wantClass: "other",
+		},
+		{
+			name:      "root reads anything",
+			e:         file(0000, 1000, 1000),
+			id:        identity{Name: "root"},
+			wantPerm:  "rw-",
+			wantClass: "root",
+		},
+		{
+			name:      "root searches directories",
+			e:         file(fs.ModeDir|0000, 1000, 1000),
+			id:        identity{Name: "root"},
+			wantPerm:  "rwx",
+			wantClass: "root",
+		},
+		{
+			name: "acl named user limited by mask",
+			e:    file(0750, 0, 0),
+			id:   alice,
+			acl: []aclEntry{
+				{Tag: aclUserObj, Perm: 07},
+				{Tag: aclUser, Perm: 07, ID: 1000},
+				{Tag: aclGroupObj, Perm: 05},
+				{Tag: aclMask, Perm: 05},
+				{Tag: aclOther, Perm: 0},
+			},
+			wantPerm:  "r-x",
+			wantClass: "user:alice",
+		},
+		{
+			name: "acl named group",
+			e:    file(0700, 0, 0),
+			id:   alice,
+			acl: []aclEntry{
+				{Tag: aclUserObj, Perm: 07},
+				{Tag: aclGroupObj, Perm: 0},
+				{Tag: aclGroup, Perm: 04, ID: 27},
+				{Tag: aclMask, Perm: 07},
+				{Tag: aclOther, Perm: 01},
+			},
+			wantPerm:  "r--",
+			wantClass: "group:27",
+		},
+		{
+			name: "acl matching group never falls back to other",
+			e:    file(0701, 0, 1000),
+			id:   alice,
+			acl: []aclEntry{
+				{Tag: aclUserObj, Perm: 07},
+				{Tag: aclGroupObj, Perm: 0},
+				{Tag: aclMask, Perm: 07},
+				{Tag: aclOther, Perm: 01},
+			},
+			wantPerm:  "---",
+			wantClass: "group",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			perm, class := effectivePerm(tt.e, tt.id, tt.acl)
+			if fmtPerm(perm) != tt.wantPerm || class != tt.wantClass {
+				t.Errorf("effectivePerm() = %s as %s, want %s as %s",
+					fmtPerm(perm), class, tt.wantPerm, tt.wantClass)
+			}
+		})
+	}
+}
+
+// TestEvalAccess tests locating the first component that denies access.
+func TestEvalAccess(t *testing.T) {
+	nobody := identity{Name: "nobody", Uid: 65534, Gid: 65534}
+
+	entries := []entry{
+		{Name: "/", Info: mockFileInfo{mode: fs.ModeDir | 0755}},
+		{Name: "priv", Info: mockFileInfo{mode: fs.ModeDir | 0700}},
+		{Name: "sub", Info: mockFileInfo{mode: fs.ModeDir | 0700}},
+		{Name: "file", Info: mockFileInfo{mode: 0644}},
+	}
+
+	evalAccess(entries, nobody)
+
+	wantNeed := []string{"search", "search", "search", "read,execute"}
+	wantDenied := []bool{false, true, true, false}
+	for i, e := range entries {
+		if e.Access == nil {
+			t.Fatalf("evalAccess() entry %q has no access result", e.Name)
+		}
+		if e.Access.Need != wantNeed[i] || e.Access.Denied != wantDenied[i] {
+			t.Errorf("evalAccess() entry %q = %+v, want need %q denied %v",
+				e.Name, *e.Access, wantNeed[i], wantDenied[i])
+		}
+	}
+
+	if i := firstDenied(entries); i != 1 || !entries[i].Access.first {
+		t.Errorf("firstDenied() = %d, want 1 marked as first", i)
+	}
+	if entries[2].Access.first {
+		t.Error("evalAccess() marked a later denial as first")
+	}
+}
+
+// TestLookupIdentity tests resolving identities by name and numeric ID.
+func TestLookupIdentity(t *testing.T) {
+	for _, spec := range []string{"root", "0", "root:0", "0:root"} {
+		t.Run(spec, func(t *testing.T) {
+			id, err := lookupIdentity(spec)
+			if err != nil {
+				t.Skipf("lookupIdentity(%q) unavailable: %v", spec, err)
+			}
+			if id.Uid != 0 || id.Gid != 0 || id.Name != "root" {
+				t.Errorf("lookupIdentity(%q) = %v, want root(0:0)", spec, id)
+			}
+		})
+	}
+
+	for _, spec := range []string{"", ":root", "no-such-user-lsi", "root:no-such-group-lsi"} {
+		if _, err := lookupIdentity(spec); err == nil {
+			t.Errorf("lookupIdentity(%q) error = nil, want error", spec)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+// Inode attribute flags as returned by the FS_IOC_GETFLAGS ioctl (FS_*_FL in
+// linux/fs.h). These are defined here rather than taken from a system package
+// so that they can be formatted on any platform.
+const (
+	attrSecureRm    = 0x00000001
+	attrUndelete    = 0x00000002
+	attrCompress    = 0x00000004
+	attrSync        = 0x00000008
+	attrImmutable   = 0x00000010
+	attrAppend      = 0x00000020
+	attrNoDump      = 0x00000040
+	attrNoAtime     = 0x00000080
+	attrNoCompress  = 0x00000400
+	attrEncrypt     = 0x00000800
+	attrIndex       = 0x00001000
+	attrJournalData = 0x00004000
+	attrNoTail      = 0x00008000
+	attrDirSync     = 0x00010000
+	attrTopDir      = 0x00020000
+	attrExtents     = 0x00080000
+	attrVerity      = 0x00100000
+	attrNoCow       = 0x00800000
+	attrDax         = 0x02000000
+	attrInlineData  = 0x10000000
+	attrProjInherit = 0x20000000
+	attrCasefold    = 0x40000000
+)
+
+// attrLetters maps each inode attribute flag to its lsattr(1) letter, in the
+// order lsattr prints them.
+var attrLetters = []struct {
+	flag   uint32
+	letter byte
+}{
+	{attrSecureRm, 's'},
+	{attrUndelete, 'u'},
+	{attrSync, 'S'},
+	{attrDirSync, 'D'},
+	{attrImmutable, 'i'},
+	{attrAppend, 'a'},
+	{attrNoDump, 'd'},
+	{attrNoAtime, 'A'},
+	{attrCompress, 'c'},
+	{attrEncrypt, 'E'},
+	{attrJournalData, 'j'},
+	{attrIndex, 'I'},
+	{attrNoTail, 't'},
+	{attrTopDir, 'T'},
+	{attrExtents, 'e'},
+	{attrNoCow, 'C'},
+	{attrDax, 'x'},
+	{attrCasefold, 'F'},
+	{attrInlineData, 'N'},
+	{attrProjInherit, 'P'},
+	{attrVerity, 'V'},
+	{attrNoCompress, 'm'},
+}
+
+// fmtAttributes returns the lsattr-style string for the given inode flags,
+// with a dash in place of each unset flag.
+func fmtAttributes(flags uint32) string {
+	s := make([]byte, len(attrLetters))
+	for i, a := range attrLetters {
+		s[i] = '-'
+		if 0 != flags&a.flag {
+			s[i] = a.letter
+		}
+	}
+	return string(s)
+}
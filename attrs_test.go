@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestFmtAttributes tests lsattr-style formatting of inode flags.
+func TestFmtAttributes(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags uint32
+		want  string
+	}{
+		{
+			name:  "no flags",
+			flags: 0,
+			want:  "----------------------",
+		},
+		{
+			name:  "immutable extents",
+			flags: attrImmutable | attrExtents,
+			want:  "----i---------e-------",
+		},
+		{
+			name:  "append only no dump",
+			flags: attrAppend | attrNoDump,
+			want:  "-----ad---------------",
+		},
+		{
+			name:  "casefold dax",
+			flags: attrCasefold | attrDax,
+			want:  "----------------xF----",
+		},
+		{
+			name:  "unknown bits ignored",
+			flags: 0x00000100,
+			want:  "----------------------",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmtAttributes(tt.flags); got != tt.want {
+				t.Errorf("fmtAttributes(%#x) = %q, want %q", tt.flags, got, tt.want)
+			}
+		})
+	}
+}
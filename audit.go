@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// Rules reported by --audit.
+const (
+	ruleAuditWritable = "audit-writable"
+	ruleAuditOwner    = "audit-owner"
+	ruleAuditSymlink  = "audit-symlink"
+)
+
+// lookupTrusted resolves a comma-separated list of users, given by name or
+// numeric ID, into the set of trusted UIDs. The superuser is always trusted.
+func lookupTrusted(spec string) (map[int]bool, error) {
+	trusted := map[int]bool{0: true}
+	for _, name := range strings.Split(spec, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		u, err := user.Lookup(name)
+		if err != nil {
+			var e error
+			if u, e = user.LookupId(name); e != nil {
+				return nil, err
+			}
+		}
+		uid, err := strconv.Atoi(u.Uid)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: invalid uid: %s", u.Username, u.Uid)
+		}
+		trusted[uid] = true
+	}
+	return trusted, nil
+}
+
+// auditEntries attaches a finding to each entry that an untrusted user could
+// use to redirect resolution of the path, in the manner of OpenSSH
+// StrictModes: entries writable by group or others, entries owned by an
+// untrusted user, and symlinks owned by an untrusted user. The sticky bit
+// only protects the contents of directories, so it exempts nothing else.
+func auditEntries(entries []entry, trusted map[int]bool) {
+	for i := range entries {
+		e := &entries[i]
+		if e.Err != nil || e.Info == nil {
+			continue
+		}
+
+		if e.Link != "" {
+			// The mode of a symlink is meaningless; only its owner can
+			// change where it points.
+			if !trusted[e.Uid] {
+				e.Findings = append(e.Findings, newFinding(ruleAuditSymlink,
+					"symlink owned by untrusted user "+e.User))
+			}
+			continue
+		}
+
+		m := e.Info.Mode()
+		if 0 == m&fs.ModeSticky || !m.IsDir() {
+			var by []string
+			if 0 != m.Perm()&0020 {
+				by = append(by, "group "+e.Group)
+			}
+			if 0 != m.Perm()&0002 {
+				by = append(by, "others")
+			}
+			if len(by) > 0 {
+				e.Findings = append(e.Findings, newFinding(ruleAuditWritable,
+					"writable by "+strings.Join(by, " and ")+" without the sticky bit"))
+			}
+		}
+
+		if !trusted[e.Uid] {
+			e.Findings = append(e.Findings, newFinding(ruleAuditOwner,
+				"owned by untrusted user "+e.User))
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// TestAuditEntries tests detection of entries an untrusted user controls.
+func TestAuditEntries(t *testing.T) {
+	trusted := map[int]bool{0: true, 1000: true}
+
+	tests := []struct {
+		name      string
+		e         entry
+		wantRules []string
+		wantMsg   string
+	}{
+		{
+			name: "trusted directory",
+			e:    entry{Name: "etc", User: "root", Info: mockFileInfo{mode: fs.ModeDir | 0755}},
+		},
+		{
+			name: "sticky world-writable directory",
+			e:    entry{Name: "tmp", User: "root", Info: mockFileInfo{mode: fs.ModeDir | fs.ModeSticky | 0777}},
+		},
+		{
+			name:      "world-writable directory",
+			e:         entry{Name: "drop", User: "root", Group: "root", Info: mockFileInfo{mode: fs.ModeDir | 0777}},
+			wantRules: []string{ruleAuditWritable},
+			wantMsg:   "writable by group root and others without the sticky bit",
+		},
+		{
+			name:      "group-writable file",
+			e:         entry{Name: "keys", User: "root", Group: "staff", Info: mockFileInfo{mode: 0664}},
+			wantRules: []string{ruleAuditWritable},
+			wantMsg:   "writable by group staff without the sticky bit",
+		},
+		{
+			name:      "sticky bit does not protect files",
+			e:         entry{Name: "keys", User: "root", Info: mockFileInfo{mode: fs.ModeSticky | 0602}},
+			wantRules: []string{ruleAuditWritable},
+			wantMsg:   "writable by others",
+		},
+		{
+			name:      "untrusted owner",
+			e:         entry{Name: "home", Uid: 1001, User: "mallory", Info: mockFileInfo{mode: fs.ModeDir | 0755}},
+			wantRules: []string{ruleAuditOwner},
+			wantMsg:   "owned by untrusted user mallory",
+		},
+		{
+			name: "configured trusted owner",
+			e:    entry{Name: "home", Uid: 1000, User: "alice", Info: mockFileInfo{mode: fs.ModeDir | 0700}},
+		},
+		{
+			name: "untrusted symlink",
+			e: entry{Name: "conf", Link: "/tmp/conf", Uid: 1001, User: "mallory",
+				Info: mockFileInfo{mode: fs.ModeSymlink | 0777}},
+			wantRules: []string{ruleAuditSymlink},
+			wantMsg:   "symlink owned by untrusted user mallory",
+		},
+		{
+			name: "trusted symlink mode ignored",
+			e:    entry{Name: "conf", Link: "/etc/conf", User: "root", Info: mockFileInfo{mode: fs.ModeSymlink | 0777}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := []entry{tt.e}
+			auditEntries(entries, trusted)
+
+			var rules, msgs []string
+			for _, f := range entries[0].Findings {
+				rules = append(rules, f.Rule)
+				msgs = append(msgs, f.Message)
+			}
+			if strings.Join(rules, ",") != strings.Join(tt.wantRules, ",") {
+				t.Fatalf("auditEntries() rules = %v, want %v", rules, tt.wantRules)
+			}
+			if tt.wantMsg != "" && !strings.Contains(strings.Join(msgs, "\n"), tt.wantMsg) {
+				t.Errorf("auditEntries() messages = %q, want to contain %q", msgs, tt.wantMsg)
+			}
+		})
+	}
+}
+
+// TestLookupTrusted tests resolving the trusted user set.
+func TestLookupTrusted(t *testing.T) {
+	trusted, err := lookupTrusted("")
+	if err != nil {
+		t.Fatalf("lookupTrusted() error = %v", err)
+	}
+	if len(trusted) != 1 || !trusted[0] {
+		t.Errorf("lookupTrusted() = %v, want only root", trusted)
+	}
+
+	if trusted, err := lookupTrusted("65534, 0"); err == nil && !trusted[65534] {
+		t.Errorf("lookupTrusted() = %v, want 65534 trusted", trusted)
+	}
+
+	if _, err := lookupTrusted("root,no-such-user-lsi"); err == nil {
+		t.Error("lookupTrusted() error = nil, want unknown user error")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ruleBeneathEscape is reported by --beneath for links that leave the tree.
+const ruleBeneathEscape = "beneath-escape"
+
+// escape describes the point at which following a symlink leaves a tree.
+type escape struct {
+	// Component is the path component whose resolution left the tree, and
+	// Target the symlink target it was taken from.
+	Component string
+	Target    string
+	// Resolved is the path reached by resolving Component.
+	Resolved string
+}
+
+// describe explains where resolution leaves dir.
+func (x escape) describe(dir string) string {
+	return fmt.Sprintf("leaves %s at %q in %s, reaching %s", dir, x.Component, x.Target, x.Resolved)
+}
+
+// evalBeneath attaches a finding to each symlink located inside dir whose
+// target, once resolved, leaves dir, and records on every such symlink
+// whether it is contained.
+func evalBeneath(entries []entry, dir string) error {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	root = physicalPath(root)
+
+	for i := range entries {
+		e := &entries[i]
+		if e.Err != nil || e.Link == "" {
+			continue
+		}
+		x, inside, err := escapeBeneath(e.Dest, e.Link, root)
+		if !inside {
+			continue
+		}
+		contained := err == nil && x == nil
+		e.Contained = &contained
+		switch {
+		case err != nil:
+			e.Findings = append(e.Findings, newFinding(ruleBeneathEscape, err.Error()))
+		case x != nil:
+			e.Findings = append(e.Findings, newFinding(ruleBeneathEscape, x.describe(dir)))
+		}
+	}
+	return nil
+}
+
+// escapeBeneath resolves the target of the symlink at link one component at
+// a time, following nested symlinks the way the kernel does, and returns the
+// first point at which the resolved path leaves root (which must be clean and
+// free of symlinks). An absolute target restarts resolution at "/", from
+// which it may only descend towards root until reaching it; one that never
+// reaches root leaves it at its last component. It reports whether the
+// symlink itself lies inside root; if not, nothing is resolved.
+func escapeBeneath(link, target, root string) (x *escape, inside bool, err error) {
+	abs, err := filepath.Abs(link)
+	if err != nil {
+		return nil, false, err
+	}
+	cur := physicalPath(filepath.Dir(abs))
+	if !isBeneath(filepath.Join(cur, filepath.Base(abs)), root) {
+		return nil, false, nil
+	}
+
+	type component struct{ name, target string }
+	split := func(target string) []component {
+		var c []component
+		for _, name := range strings.Split(filepath.ToSlash(target), "/") {
+			if name != "" && name != "." {
+				c = append(c, component{name, target})
+			}
+		}
+		return c
+	}
+
+	// entered reports whether resolution has reached root, and last is the
+	// component resolved most recently.
+	entered := isBeneath(cur, root)
+	last := component{".", target}
+	restart := func(target string) {
+		cur = filepath.VolumeName(target) + string(filepath.Separator)
+		entered, last = false, component{string(filepath.Separator), target}
+	}
+
+	pending := split(target)
+	if filepath.IsAbs(target) {
+		restart(target)
+	}
+	for followed := 0; len(pending) > 0; {
+		c := pending[0]
+		pending = pending[1:]
+
+		next := filepath.Join(cur, c.name)
+		if c.name != ".." {
+			info, err := os.Lstat(next)
+			if err == nil && 0 != info.Mode()&fs.ModeSymlink {
+				if followed++; followed > maxSymlinks {
+					return nil, true, errSymlinkLoop
+				}
+				nested, err := os.Readlink(next)
+				if err != nil {
+					return nil, true, err
+				}
+				if filepath.IsAbs(nested) {
+					restart(nested)
+				}
+				pending = append(split(nested), pending...)
+				continue
+			}
+		}
+
+		cur, last = next, c
+		switch {
+		case isBeneath(cur, root):
+			entered = true
+		case entered || !isBeneath(root, cur):
+			return &escape{c.name, c.target, cur}, true, nil
+		}
+	}
+	if !entered {
+		return &escape{last.name, last.target, cur}, true, nil
+	}
+	return nil, true, nil
+}
+
+// physicalPath returns the path with all symlinks resolved, or the clean
+// path itself if it cannot be resolved.
+func physicalPath(name string) string {
+	if p, err := filepath.EvalSymlinks(name); err == nil {
+		return p
+	}
+	return filepath.Clean(name)
+}
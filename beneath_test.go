@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestEscapeBeneath tests locating where symlink resolution leaves a tree.
+func TestEscapeBeneath(t *testing.T) {
+	base := physicalPath(t.TempDir())
+	root := filepath.Join(base, "app")
+	for _, dir := range []string{"app/releases/v1", "app/shared", "outside"} {
+		if err := os.MkdirAll(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"app/releases/shared":  "../shared",
+		"app/releases/abs":     "/etc",
+		"app/releases/up":      "../../outside",
+		"app/releases/via":     "shared/../../outside",
+		"app/releases/nested":  "hop/v1",
+		"app/releases/hop":     "../../outside",
+		"app/releases/current": "v1/../v1",
+		"app/releases/loop1":   "loop2",
+		"app/releases/loop2":   "loop1",
+		"outside/back":         "../app",
+		"app/releases/absin":   base + "/app/shared",
+		"app/releases/absout":  base + "/app/shared/../../outside",
+		"app/releases/absbase": base,
+		"app/releases/absroot": "/",
+		"app/releases/viaabs":  "absin/../releases",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(base, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		link       string
+		wantInside bool
+		wantErr    error
+		want       *escape
+	}{
+		{link: "app/releases/shared", wantInside: true},
+		{link: "app/releases/current", wantInside: true},
+		{
+			link:       "app/releases/abs",
+			wantInside: true,
+			want:       &escape{"etc", "/etc", "/etc"},
+		},
+		{link: "app/releases/absin", wantInside: true},
+		{link: "app/releases/viaabs", wantInside: true},
+		{
+			link:       "app/releases/absout",
+			wantInside: true,
+			want:       &escape{"..", base + "/app/shared/../../outside", base},
+		},
+		{
+			// Resolution from "/" stops short of the tree.
+			link:       "app/releases/absbase",
+			wantInside: true,
+			want:       &escape{filepath.Base(base), base, base},
+		},
+		{
+			link:       "app/releases/absroot",
+			wantInside: true,
+			want:       &escape{"/", "/", "/"},
+		},
+		{
+			link:       "app/releases/up",
+			wantInside: true,
+			want:       &escape{"..", "../../outside", base},
+		},
+		{
+			// The nested link resolves to app/shared, whose parent is app,
+			// whose parent is outside the tree.
+			link:       "app/releases/via",
+			wantInside: true,
+			want:       &escape{"..", "shared/../../outside", base},
+		},
+		{
+			link:       "app/releases/nested",
+			wantInside: true,
+			want:       &escape{"..", "../../outside", base},
+		},
+		{
+			link:       "app/releases/loop1",
+			wantInside: true,
+			wantErr:    errSymlinkLoop,
+		},
+		{link: "outside/back"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			name := filepath.Join(base, tt.link)
+			target, err := os.Readlink(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			x, inside, err := escapeBeneath(name, target, root)
+			if inside != tt.wantInside || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("escapeBeneath() inside = %v, error = %v, want %v, %v", inside, err, tt.wantInside, tt.wantErr)
+			}
+			switch {
+			case tt.want == nil && x != nil:
+				t.Errorf("escapeBeneath() = %+v, want contained", *x)
+			case tt.want != nil && (x == nil || *x != *tt.want):
+				t.Errorf("escapeBeneath() = %+v, want %+v", x, *tt.want)
+			}
+		})
+	}
+}
+
+// TestEvalBeneath tests that escaping links are marked with a finding.
+func TestEvalBeneath(t *testing.T) {
+	base := physicalPath(t.TempDir())
+	if err := os.MkdirAll(filepath.Join(base, "tree", "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{"tree/ok": "lib", "tree/bad": "../..", "top": "tree"} {
+		if err := os.Symlink(target, filepath.Join(base, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries := []entry{
+		{Name: "top", Dest: filepath.Join(base, "top"), Link: "tree"},
+		{Name: "ok", Dest: filepath.Join(base, "tree", "ok"), Link: "lib"},
+		{Name: "bad", Dest: filepath.Join(base, "tree", "bad"), Link: "../.."},
+		{Name: "lib", Dest: filepath.Join(base, "tree", "lib")},
+	}
+	if err := evalBeneath(entries, filepath.Join(base, "tree")); err != nil {
+		t.Fatalf("evalBeneath() error = %v", err)
+	}
+
+	if entries[0].Contained != nil || len(entries[0].Findings) != 0 {
+		t.Errorf("evalBeneath() checked link outside the tree: %+v", entries[0])
+	}
+	if c := entries[1].Contained; c == nil || !*c || len(entries[1].Findings) != 0 {
+		t.Errorf("evalBeneath() contained link = %v, %v, want contained", c, entries[1].Findings)
+	}
+	if c := entries[2].Contained; c == nil || *c || len(entries[2].Findings) != 1 ||
+		entries[2].Findings[0].Rule != ruleBeneathEscape {
+		t.Errorf("evalBeneath() escaping link = %v, %v, want beneath-escape finding", c, entries[2].Findings)
+	}
+	if entries[3].Contained != nil {
+		t.Error("evalBeneath() marked a directory")
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// canonicalPaths returns the canonical path of each entry: the absolute path,
+// free of symlinks and ".." components, at which the entry itself was found.
+// It is worked out from the chain alone, each link continuing from where its
+// target resolved, except for the working directory of a relative path and
+// links that were not followed.
+func canonicalPaths(entries []entry) []string {
+	canon := make([]string, len(entries))
+
+	// dir holds, for each level, the directory the next component of the
+	// walk at that level is found in.
+	var dir []string
+	for i, e := range entries {
+		switch {
+		case i == 0 || e.Level > entries[i-1].Level:
+			// A new walk starts from the directory of the link it follows,
+			// or the working directory.
+			base := "."
+			if i > 0 {
+				base = filepath.Dir(canon[i-1])
+			}
+			base, _ = filepath.Abs(base)
+			dir = append(dir[:e.Level], physicalPath(base))
+		case e.Level < entries[i-1].Level:
+			// A link continues from wherever its target resolved.
+			dir = dir[:e.Level+1]
+			dir[e.Level] = canon[i-1]
+		case entries[i-1].Link != "":
+			// The system followed a link the walk did not.
+			dir[e.Level] = physicalPath(canon[i-1])
+		default:
+			dir[e.Level] = canon[i-1]
+		}
+
+		switch {
+		case e.Name == "..":
+			canon[i] = filepath.Dir(dir[e.Level])
+		case filepath.IsAbs(e.Name):
+			canon[i] = filepath.Clean(e.Name)
+		default:
+			canon[i] = filepath.Join(dir[e.Level], e.Name)
+		}
+	}
+	return canon
+}
+
+// resolveTargets records the canonical path of each entry, and the absolute
+// form of the target of each symlink: the canonical path its target names
+// from the directory holding the link, resolving every element but the last
+// as the system would. A target that cannot be resolved is left empty.
+func resolveTargets(ctx context.Context, entries []entry) {
+	for i, canon := range canonicalPaths(entries) {
+		e := &entries[i]
+		e.Canonical = canon
+		if e.Link == "" {
+			continue
+		}
+		target := e.Link
+		if !filepath.IsAbs(target) {
+			target = filepath.Dir(canon) + string(filepath.Separator) + target
+		}
+		if t, err := canonicalizeWith(ctx, target, options{noFollowLast: true}, false, true); err == nil {
+			e.Target = t
+		}
+	}
+}
+
+// canonicalize walks path and returns its canonical path, as by readlink(1)
+// with -e, or with -f if final, or with -m if missing. With -f, the final
+// component of the resolution need not exist, and with -m no component need
+// exist; the components that could not be walked are then appended as given,
+// and a symlink loop is left unresolved at the first link repeated. Unless
+// missing, a path ending in a separator must also name a directory.
+func canonicalize(ctx context.Context, path string, final, missing bool) (string, error) {
+	return canonicalizeWith(ctx, path, options{}, final, missing)
+}
+
+// canonicalizeWith canonicalizes path as canonicalize does, following only
+// the symlinks opts.follows allows.
+func canonicalizeWith(ctx context.Context, path string, opts options, final, missing bool) (string, error) {
+	entries, err := collectEntries(ctx, path, opts)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("%s: %w", path, fs.ErrNotExist)
+	}
+	canon := canonicalPaths(entries)
+	last := len(entries) - 1
+	if err == nil {
+		if !missing && isDirPath(path) && !entries[last].Info.IsDir() {
+			return "", fmt.Errorf("%s: %w", path, syscall.ENOTDIR)
+		}
+		return canon[last], nil
+	}
+
+	// Only a missing component can be canonicalized, and with -f only if
+	// nothing remains to be walked after it.
+	e := entries[last]
+	absent := errors.Is(e.Err, fs.ErrNotExist)
+	if missing {
+		switch {
+		case errors.Is(e.Err, syscall.ENOTDIR):
+			absent = true
+		case errors.Is(e.Err, errSymlinkLoop):
+			absent, last = true, firstRepeated(entries)
+		}
+	}
+	if !absent || !(final || missing) {
+		return "", err
+	}
+
+	// Append the elements left to walk at each level, from the entry in
+	// error out through the link whose target each level was walking. As
+	// they could not be walked, they are reduced lexically.
+	resolved := canon[last]
+	for i := last; i >= 0; {
+		link := i - 1
+		walked := 1
+		for ; link >= 0 && entries[link].Level >= entries[i].Level; link-- {
+			if entries[link].Level == entries[i].Level {
+				walked++
+			}
+		}
+		target := path
+		if link >= 0 {
+			target = entries[link].Link
+		}
+		elem, _ := splitPath(target)
+		if rest := elem[min(walked, len(elem)):]; len(rest) > 0 {
+			if !missing {
+				return "", e.Err
+			}
+			resolved = filepath.Join(append([]string{resolved}, rest...)...)
+		}
+		i = link
+	}
+	return resolved, nil
+}
+
+// isDirPath reports whether path can only name a directory, since it ends
+// in a separator or a "." element.
+func isDirPath(path string) bool {
+	n := len(path)
+	return n > 1 && (os.IsPathSeparator(path[n-1]) ||
+		path[n-1] == '.' && os.IsPathSeparator(path[n-2]))
+}
+
+// firstRepeated returns the index of the first symlink of entries that was
+// already followed earlier in the walk. Without one, the chain was too long
+// rather than a loop, and the index of the last symlink is returned.
+func firstRepeated(entries []entry) int {
+	type file struct{ dev, inode uint64 }
+	seen := map[file]bool{}
+	last := len(entries) - 1
+	for i, e := range entries {
+		if e.Link == "" || e.Err != nil {
+			continue
+		}
+		f := file{e.Dev, e.Inode}
+		if seen[f] {
+			return i
+		}
+		seen[f] = true
+		last = i
+	}
+	return last
+}
+
+// printCanonical prints the canonical path of each path, ending each with a
+// NUL byte if opts.zero.
+func printCanonical(ctx context.Context, out io.Writer, paths []string, opts options) error {
+	end := "\n"
+	if opts.zero {
+		end = "\x00"
+	}
+	for _, p := range paths {
+		canon, err := canonicalize(ctx, p, opts.canonicalize, opts.canonicalizeMissing)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(out, canon, end)
+	}
+	return nil
+}
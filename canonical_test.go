@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestCanonicalize tests the canonical paths of each mode against those
+// expected from readlink(1).
+func TestCanonicalize(t *testing.T) {
+	base := physicalPath(t.TempDir())
+	for _, dir := range []string{"usr/bin", "usr/lib/python3.11", "a/b"} {
+		if err := os.MkdirAll(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(base, "d", "e"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"file", "x", filepath.Join("d", "x")} {
+		if err := os.WriteFile(filepath.Join(base, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range map[string]string{
+		"usr/bin/python3": "python",
+		"usr/bin/python":  "../lib/python3.11",
+		"lib":             filepath.Join(base, "usr", "lib"),
+		"a/b/up":          "../../a",
+		"dangling":        "usr/lib/python3.12",
+		"loop":            "loop",
+		"skip":            "nope/../file",
+		"le":              "d/e",
+		"lx":              "le/../x",
+	} {
+		if err := os.Symlink(target, filepath.Join(base, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const fail = ""
+	tests := []struct {
+		name                    string
+		path                    string
+		existing, final, absent string
+	}{
+		{"chain", "usr/bin/python3", "usr/lib/python3.11", "usr/lib/python3.11", "usr/lib/python3.11"},
+		{"through link", "lib/python3.11", "usr/lib/python3.11", "usr/lib/python3.11", "usr/lib/python3.11"},
+		{"parent through link", "a/b/up/b/up/b", "a/b", "a/b", "a/b"},
+		{"missing last", "lib/python3.12", fail, "usr/lib/python3.12", "usr/lib/python3.12"},
+		{"dangling link", "dangling", fail, "usr/lib/python3.12", "usr/lib/python3.12"},
+		{"missing parent", "lib/python3.12/site", fail, fail, "usr/lib/python3.12/site"},
+		{"missing through link", "dangling/site", fail, fail, "usr/lib/python3.12/site"},
+		{"not a directory", "file/x", fail, fail, "file/x"},
+		{"loop", "loop", fail, fail, "loop"},
+		{"through loop", "loop/x", fail, fail, "loop/x"},
+		{"missing before parent", "nope/..", fail, fail, "."},
+		{"file before parent", "file/..", fail, fail, "."},
+		{"missing before parent in link", "skip", fail, fail, "file"},
+		{"parent of link", "le/..", "d", "d", "d"},
+		{"grandparent of link", "le/../..", ".", ".", "."},
+		{"parent in link target", "lx", "d/x", "d/x", "d/x"},
+		{"sibling through link", "le/../d", fail, "d/d", "d/d"},
+		{"missing before parent through link", "le/nope/..", fail, fail, "d/e"},
+		{"trailing slash", "a/b/", "a/b", "a/b", "a/b"},
+		{"trailing slash on file", "file/", fail, fail, "file"},
+		{"trailing dot on file", "file/.", fail, fail, "file"},
+		{"trailing slash on missing", "usr/nope/", fail, "usr/nope", "usr/nope"},
+	}
+
+	// Where GNU readlink is available, each result is also checked against
+	// it directly.
+	readlink, err := exec.LookPath("readlink")
+	if err != nil || runtime.GOOS != "linux" {
+		readlink = ""
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, m := range []struct {
+				mode           string
+				final, missing bool
+				want           string
+			}{
+				{"-e", false, false, tt.existing},
+				{"-f", true, false, tt.final},
+				{"-m", false, true, tt.absent},
+			} {
+				// The path is not cleaned, since separators and ".." are significant.
+				path := base + string(filepath.Separator) + filepath.FromSlash(tt.path)
+				got, err := canonicalize(context.Background(), path, m.final, m.missing)
+				switch {
+				case m.want == fail && err == nil:
+					t.Errorf("canonicalize(%s) = %s, want error", m.mode, got)
+				case m.want != fail && err != nil:
+					t.Errorf("canonicalize(%s) error = %v", m.mode, err)
+				case m.want != fail && got != filepath.Join(base, m.want):
+					t.Errorf("canonicalize(%s) = %s, want %s", m.mode, got, filepath.Join(base, m.want))
+				}
+				if readlink != "" {
+					out, rerr := exec.Command(readlink, m.mode, path).Output()
+					if want := strings.TrimSuffix(string(out), "\n"); (rerr != nil) != (err != nil) || err == nil && got != want {
+						t.Errorf("canonicalize(%s) = %q, %v; readlink %s = %q, %v", m.mode, got, err, m.mode, want, rerr)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestCanonicalPathsRelative tests that a relative path is canonicalized
+// from the working directory.
+func TestCanonicalPathsRelative(t *testing.T) {
+	base := physicalPath(t.TempDir())
+	if err := os.Mkdir(filepath.Join(base, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("dir", filepath.Join(base, "link")); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(base)
+
+	got, err := canonicalize(context.Background(), "link", false, false)
+	if want := filepath.Join(base, "dir"); err != nil || got != want {
+		t.Errorf("canonicalize() = %s, %v, want %s", got, err, want)
+	}
+}
+
+// TestResolveTargets tests that each link gets the absolute form of its
+// target, and each entry its canonical path.
+func TestResolveTargets(t *testing.T) {
+	base := physicalPath(t.TempDir())
+	if err := os.MkdirAll(filepath.Join(base, "usr", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("usr/bin", filepath.Join(base, "bin")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../etc/vi", filepath.Join(base, "usr", "bin", "vi")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := collectEntries(context.Background(), filepath.Join(base, "bin", "vi"), options{})
+	resolveTargets(context.Background(), entries)
+
+	var links, canon []string
+	for _, e := range entries {
+		if e.Link != "" {
+			links = append(links, e.Target)
+		}
+		canon = append(canon, e.Canonical)
+	}
+	wantLinks := []string{filepath.Join(base, "usr", "bin"), filepath.Join(base, "etc", "vi")}
+	if !slices.Equal(links, wantLinks) {
+		t.Errorf("resolveTargets() targets = %v, want %v", links, wantLinks)
+	}
+	// The missing target ends the chain at the canonical path it would have.
+	if got, want := canon[len(canon)-1], filepath.Join(base, "etc"); got != want {
+		t.Errorf("resolveTargets() last canonical = %s, want %s", got, want)
+	}
+	if got, want := canon[len(canon)-2], base; got != want {
+		t.Errorf("resolveTargets() canonical of .. = %s, want %s", got, want)
+	}
+
+	// A ".." following a link in the target leaves where the link leads.
+	if err := os.MkdirAll(filepath.Join(base, "d", "e"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{"le": "d/e", "lx": "le/../x", "ly": "le"} {
+		if err := os.Symlink(target, filepath.Join(base, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, want := range map[string]string{"lx": "d/x", "ly": "le"} {
+		entries, _ = collectEntries(context.Background(), filepath.Join(base, name), options{noFollow: true})
+		resolveTargets(context.Background(), entries)
+		if got, want := entries[len(entries)-1].Target, filepath.Join(base, want); got != want {
+			t.Errorf("resolveTargets() target of %s = %s, want %s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Layout of the security.capability extended attribute (struct vfs_ns_cap_data
+// in linux/capability.h). All fields are stored little-endian.
+const (
+	vfsCapRevisionMask = 0xFF000000
+	vfsCapFlagsMask    = ^uint32(vfsCapRevisionMask)
+	vfsCapEffective    = 0x000001
+
+	vfsCapRevision1 = 0x01000000
+	vfsCapRevision2 = 0x02000000
+	vfsCapRevision3 = 0x03000000
+
+	vfsCapSize1 = 4 + 1*8
+	vfsCapSize2 = 4 + 2*8
+	vfsCapSize3 = 4 + 2*8 + 4
+)
+
+// capabilityNames lists the kernel capability names indexed by bit number.
+var capabilityNames = []string{
+	"cap_chown",
+	"cap_dac_override",
+	"cap_dac_read_search",
+	"cap_fowner",
+	"cap_fsetid",
+	"cap_kill",
+	"cap_setgid",
+	"cap_setuid",
+	"cap_setpcap",
+	"cap_linux_immutable",
+	"cap_net_bind_service",
+	"cap_net_broadcast",
+	"cap_net_admin",
+	"cap_net_raw",
+	"cap_ipc_lock",
+	"cap_ipc_owner",
+	"cap_sys_module",
+	"cap_sys_rawio",
+	"cap_sys_chroot",
+	"cap_sys_ptrace",
+	"cap_sys_pacct",
+	"cap_sys_admin",
+	"cap_sys_boot",
+	"cap_sys_nice",
+	"cap_sys_resource",
+	"cap_sys_time",
+	"cap_sys_tty_config",
+	"cap_mknod",
+	"cap_lease",
+	"cap_audit_write",
+	"cap_audit_control",
+	"cap_setfcap",
+	"cap_mac_override",
+	"cap_mac_admin",
+	"cap_syslog",
+	"cap_wake_alarm",
+	"cap_block_suspend",
+	"cap_audit_read",
+	"cap_perfmon",
+	"cap_bpf",
+	"cap_checkpoint_restore",
+}
+
+// errInvalidCapability indicates a malformed security.capability attribute.
+var errInvalidCapability = errors.New("invalid capability data")
+
+// capabilitySet holds the decoded contents of a security.capability attribute.
+type capabilitySet struct {
+	Version     int
+	Effective   bool
+	Permitted   uint64
+	Inheritable uint64
+	RootID      uint32
+}
+
+// decodeCapabilities parses the raw security.capability attribute value.
+func decodeCapabilities(data []byte) (c capabilitySet, err error) {
+	if len(data) < 4 {
+		return c, errInvalidCapability
+	}
+
+	magic := binary.LittleEndian.Uint32(data)
+	c.Effective = 0 != magic&vfsCapFlagsMask&vfsCapEffective
+
+	// Each revision extends the previous one with another 32-bit word of
+	// permitted and inheritable bits, and v3 appends the namespace root ID.
+	var words int
+	switch magic & vfsCapRevisionMask {
+	case vfsCapRevision1:
+		c.Version, words = 1, 1
+		if len(data) != vfsCapSize1 {
+			return c, errInvalidCapability
+		}
+	case vfsCapRevision2:
+		c.Version, words = 2, 2
+		if len(data) != vfsCapSize2 {
+			return c, errInvalidCapability
+		}
+	case vfsCapRevision3:
+		c.Version, words = 3, 2
+		if len(data) != vfsCapSize3 {
+			return c, errInvalidCapability
+		}
+		c.RootID = binary.LittleEndian.Uint32(data[vfsCapSize2:])
+	default:
+		return c, errInvalidCapability
+	}
+
+	for i := range words {
+		off := 4 + i*8
+		c.Permitted |= uint64(binary.LittleEndian.Uint32(data[off:])) << (32 * i)
+		c.Inheritable |= uint64(binary.LittleEndian.Uint32(data[off+4:])) << (32 * i)
+	}
+
+	return c, nil
+}
+
+// String returns the capability set in the textual form used by getcap(8),
+// e.g. "cap_net_admin,cap_net_raw=ep". Capabilities sharing the same flags are
+// grouped into a single clause.
+func (c capabilitySet) String() string {
+	var (
+		clause []string
+		order  []string
+		names  = map[string][]string{}
+	)
+
+	for bit := range 64 {
+		var flags string
+		if c.Effective && 0 != (c.Permitted|c.Inheritable)&(1<<bit) {
+			flags += "e"
+		}
+		if 0 != c.Inheritable&(1<<bit) {
+			flags += "i"
+		}
+		if 0 != c.Permitted&(1<<bit) {
+			flags += "p"
+		}
+		if flags == "" {
+			continue
+		}
+		if _, ok := names[flags]; !ok {
+			order = append(order, flags)
+		}
+		names[flags] = append(names[flags], capabilityName(bit))
+	}
+
+	for _, flags := range order {
+		clause = append(clause, strings.Join(names[flags], ",")+"="+flags)
+	}
+
+	s := strings.Join(clause, " ")
+	if c.RootID != 0 {
+		s += fmt.Sprintf(" [rootid=%d]", c.RootID)
+	}
+	return s
+}
+
+// capabilityName returns the name of the capability with the given bit number.
+func capabilityName(bit int) string {
+	if bit < len(capabilityNames) {
+		return capabilityNames[bit]
+	}
+	return fmt.Sprintf("cap_%d", bit)
+}
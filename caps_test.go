@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// capData builds a raw security.capability attribute value.
+func capData(magic uint32, words ...uint32) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, magic)
+	for _, w := range words {
+		b = binary.LittleEndian.AppendUint32(b, w)
+	}
+	return b
+}
+
+// TestDecodeCapabilities tests decoding of each VFS capability revision.
+func TestDecodeCapabilities(t *testing.T) {
+	const bindService = 1 << 10 // cap_net_bind_service
+	const netAdminRaw = 1<<12 | 1<<13
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		version int
+		wantErr bool
+	}{
+		{
+			name:    "v1 effective",
+			data:    capData(vfsCapRevision1|vfsCapEffective, bindService, 0),
+			want:    "cap_net_bind_service=ep",
+			version: 1,
+		},
+		{
+			name:    "v2 permitted only",
+			data:    capData(vfsCapRevision2, netAdminRaw, 0, 0, 0),
+			want:    "cap_net_admin,cap_net_raw=p",
+			version: 2,
+		},
+		{
+			name:    "v2 mixed flags",
+			data:    capData(vfsCapRevision2|vfsCapEffective, bindService, 1<<0, 0, 0),
+			want:    "cap_chown=ei cap_net_bind_service=ep",
+			version: 2,
+		},
+		{
+			name:    "v2 upper word",
+			data:    capData(vfsCapRevision2|vfsCapEffective, 0, 0, 1<<(39-32), 0),
+			want:    "cap_bpf=ep",
+			version: 2,
+		},
+		{
+			name:    "v2 unknown capability",
+			data:    capData(vfsCapRevision2, 0, 0, 1<<(63-32), 0),
+			want:    "cap_63=p",
+			version: 2,
+		},
+		{
+			name:    "v3 rootid",
+			data:    capData(vfsCapRevision3|vfsCapEffective, bindService, 0, 0, 0, 100000),
+			want:    "cap_net_bind_service=ep [rootid=100000]",
+			version: 3,
+		},
+		{
+			name:    "truncated",
+			data:    []byte{0x00, 0x00},
+			wantErr: true,
+		},
+		{
+			name:    "wrong size for revision",
+			data:    capData(vfsCapRevision2, bindService, 0),
+			wantErr: true,
+		},
+		{
+			name:    "unknown revision",
+			data:    capData(0x04000000, bindService, 0, 0, 0),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCapabilities(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCapabilities() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Version != tt.version {
+				t.Errorf("decodeCapabilities() Version = %d, want %d", got.Version, tt.version)
+			}
+			if s := got.String(); s != tt.want {
+				t.Errorf("decodeCapabilities().String() = %q, want %q", s, tt.want)
+			}
+		})
+	}
+}
@@ -83,7 +83,7 @@ _lsi_completion() {
     prev="${COMP_WORDS[COMP_CWORD-1]}"
     
     # All available flags
-    opts="-h --help -v --version -t --timeout -n --no-follow -l --long -p --permissions -u --user -g --group -s --size -i --inode -m --mount"
+    opts="-h --help -v --version -t --timeout -n --no-follow --max-depth --no-follow-last --follow-last -l --long -p --permissions --mode-style -a --attrs -N --nlink -u --user -g --group -s --size -b --blocks -B --blksize -H --human --si -i --inode -m --mount -c --caps -d --device -T --type --digest --format --as --audit --trust --policy --beneath --race --concurrent --watch --on-change --jobs --max-hops --in --exclude --one-file-system --tree --absolute --links-only -f --canonicalize -e --canonicalize-existing --canonicalize-missing -z --zero"
     
     # Handle timeout flag requiring a value
     if [[ "${prev}" == "-t" || "${prev}" == "--timeout" ]]; then
@@ -92,6 +92,24 @@ _lsi_completion() {
         return 0
     fi
     
+    # Handle mode-style flag requiring a value
+    if [[ "${prev}" == "--mode-style" ]]; then
+        COMPREPLY=( $(compgen -W "symbolic octal both" -- "${cur}") )
+        return 0
+    fi
+    
+    # Handle digest flag requiring a value
+    if [[ "${prev}" == "--digest" ]]; then
+        COMPREPLY=( $(compgen -W "sha256 sha512 blake2b" -- "${cur}") )
+        return 0
+    fi
+    
+    # Handle format flag requiring a value
+    if [[ "${prev}" == "--format" ]]; then
+        COMPREPLY=( $(compgen -W "text json sarif" -- "${cur}") )
+        return 0
+    fi
+    
     # Complete flags
     if [[ "${cur}" == -* ]]; then
         COMPREPLY=( $(compgen -W "${opts}" -- "${cur}") )
@@ -126,13 +144,49 @@ _lsi() {
         '(-v --version)'{-v,--version}'[Display version information]'
         '(-t --timeout)'{-t,--timeout}'[Timeout duration (e.g., 30s, 5m)]:duration:(30s 1m 5m 10m)'
         '(-n --no-follow)'{-n,--no-follow}'[Do not follow symlinks]'
+        '--max-depth[Do not follow symlinks beyond N levels of indentation]:depth:'
+        '--no-follow-last[Follow symlinks except the last component of each PATH]'
+        '--follow-last[Follow only the symlink of the last component of each PATH]'
         '(-l --long)'{-l,--long}'[Output using long format]'
         '(-p --permissions)'{-p,--permissions}'[Output file type and permissions]'
+        '--mode-style[Permissions style: symbolic, octal, both]:style:(symbolic octal both)'
+        '(-a --attrs)'{-a,--attrs}'[Output inode attribute flags (lsattr)]'
+        '(-N --nlink)'{-N,--nlink}'[Output hard link count]'
         '(-u --user)'{-u,--user}'[Output file owner]'
         '(-g --group)'{-g,--group}'[Output file group]'
         '(-s --size)'{-s,--size}'[Output file size (bytes)]'
+        '(-b --blocks)'{-b,--blocks}'[Output allocated size (bytes)]'
+        '(-B --blksize)'{-B,--blksize}'[Output preferred I/O block size (bytes)]'
+        '(-H --human)'{-H,--human}'[Output sizes in powers of 1024 (KiB, MiB)]'
+        '--si[Output sizes in powers of 1000 (kB, MB)]'
         '(-i --inode)'{-i,--inode}'[Output file inode]'
         '(-m --mount)'{-m,--mount}'[Output mount point symbols]'
+        '(-c --caps)'{-c,--caps}'[Output file capabilities]'
+        '(-d --device)'{-d,--device}'[Output device node name and driver]'
+        '(-T --type)'{-T,--type}'[Output content type of the final target]'
+        '--digest[Output digest of the final target]:algorithm:(sha256 sha512 blake2b)'
+        '--format[Output format]:format:(text json sarif)'
+        '--as[Simulate access as USER\[:GROUP\]]:user:_users'
+        '--audit[Report entries an untrusted user could modify]'
+        '--trust[Trust USER\[,USER...\] in addition to root]:users:_sequence _users'
+        '--policy[Policy FILE of rules evaluated by check]:file:_files'
+        '--beneath[Report symlinks in DIR resolving outside it]:directory:_files -/'
+        '--race[Walk each PATH N times and report entries that changed]:count:'
+        '--concurrent[Run the walks of --race concurrently]'
+        '--watch[Print a diff each time a PATH resolves differently]'
+        '--on-change[Run CMD when a watched target or link changes (implies --watch)]:command:_cmdstring'
+        '--jobs[Resolve up to N symlinks at once in scan]:count:'
+        '--max-hops[Report chains of more than N links in scan (default 8)]:count:'
+        '--in[Search DIR\[,DIR...\] for refs (default /)]:directories:_sequence _files -/'
+        '--exclude[Skip PATTERN\[,PATTERN...\] in scan and refs]:pattern:'
+        '--one-file-system[Stay on the filesystem of each DIR in scan and refs]'
+        '--tree[Merge the chains of all PATHs into one tree]'
+        '--absolute[Output absolute link targets and the canonical path of each entry]'
+        '--links-only[Output only symlinks, mount points, errors and the endpoint]'
+        '(-f --canonicalize)'{-f,--canonicalize}'[Print only the canonical path; all but the last component must exist]'
+        '(-e --canonicalize-existing)'{-e,--canonicalize-existing}'[Print only the canonical path; all components must exist]'
+        '--canonicalize-missing[Print only the canonical path; no component need exist (-m is --mount)]'
+        '(-z --zero)'{-z,--zero}'[End each canonical path with NUL, not newline]'
         '*:file:_files'
     )
     
@@ -159,13 +213,49 @@ complete -c lsi -s h -l help -d 'Display help message'
 complete -c lsi -s v -l version -d 'Display version information'
 complete -c lsi -s t -l timeout -d 'Timeout duration' -x -a '30s 1m 5m 10m'
 complete -c lsi -s n -l no-follow -d 'Do not follow symlinks'
+complete -c lsi -l max-depth -d 'Do not follow symlinks beyond N levels of indentation' -x
+complete -c lsi -l no-follow-last -d 'Follow symlinks except the last component of each PATH'
+complete -c lsi -l follow-last -d 'Follow only the symlink of the last component of each PATH'
 complete -c lsi -s l -l long -d 'Output using long format'
 complete -c lsi -s p -l permissions -d 'Output file type and permissions'
+complete -c lsi -l mode-style -d 'Permissions style: symbolic, octal, both' -x -a 'symbolic octal both'
+complete -c lsi -s a -l attrs -d 'Output inode attribute flags (lsattr)'
+complete -c lsi -s N -l nlink -d 'Output hard link count'
 complete -c lsi -s u -l user -d 'Output file owner'
 complete -c lsi -s g -l group -d 'Output file group'
 complete -c lsi -s s -l size -d 'Output file size (bytes)'
+complete -c lsi -s b -l blocks -d 'Output allocated size (bytes)'
+complete -c lsi -s B -l blksize -d 'Output preferred I/O block size (bytes)'
+complete -c lsi -s H -l human -d 'Output sizes in powers of 1024 (KiB, MiB)'
+complete -c lsi -l si -d 'Output sizes in powers of 1000 (kB, MB)'
 complete -c lsi -s i -l inode -d 'Output file inode'
 complete -c lsi -s m -l mount -d 'Output mount point symbols'
+complete -c lsi -s c -l caps -d 'Output file capabilities'
+complete -c lsi -s d -l device -d 'Output device node name and driver'
+complete -c lsi -s T -l type -d 'Output content type of the final target'
+complete -c lsi -l digest -d 'Output digest of the final target' -x -a 'sha256 sha512 blake2b'
+complete -c lsi -l format -d 'Output format' -x -a 'text json sarif'
+complete -c lsi -l as -d 'Simulate access as USER[:GROUP]' -x -a '(__fish_complete_users)'
+complete -c lsi -l audit -d 'Report entries an untrusted user could modify'
+complete -c lsi -l trust -d 'Trust USER[,USER...] in addition to root' -x -a '(__fish_complete_users)'
+complete -c lsi -l policy -d 'Policy FILE of rules evaluated by check' -r -F
+complete -c lsi -l beneath -d 'Report symlinks in DIR resolving outside it' -x -a '(__fish_complete_directories)'
+complete -c lsi -l race -d 'Walk each PATH N times and report entries that changed' -x
+complete -c lsi -l concurrent -d 'Run the walks of --race concurrently'
+complete -c lsi -l watch -d 'Print a diff each time a PATH resolves differently'
+complete -c lsi -l on-change -d 'Run CMD when a watched target or link changes (implies --watch)' -x -a '(__fish_complete_command)'
+complete -c lsi -l jobs -d 'Resolve up to N symlinks at once in scan' -x
+complete -c lsi -l max-hops -d 'Report chains of more than N links in scan (default 8)' -x
+complete -c lsi -l in -d 'Search DIR[,DIR...] for refs (default /)' -x -a '(__fish_complete_directories)'
+complete -c lsi -l exclude -d 'Skip PATTERN[,PATTERN...] in scan and refs' -x
+complete -c lsi -l one-file-system -d 'Stay on the filesystem of each DIR in scan and refs'
+complete -c lsi -l tree -d 'Merge the chains of all PATHs into one tree'
+complete -c lsi -l absolute -d 'Output absolute link targets and the canonical path of each entry'
+complete -c lsi -l links-only -d 'Output only symlinks, mount points, errors and the endpoint'
+complete -c lsi -s f -l canonicalize -d 'Print only the canonical path; all but the last component must exist'
+complete -c lsi -s e -l canonicalize-existing -d 'Print only the canonical path; all components must exist'
+complete -c lsi -l canonicalize-missing -d 'Print only the canonical path; no component need exist (-m is --mount)'
+complete -c lsi -s z -l zero -d 'End each canonical path with NUL, not newline'
 
 # File path completion (default behavior)
 complete -c lsi -f -a '(__fish_complete_path)'
@@ -194,20 +284,67 @@ Register-ArgumentCompleter -CommandName lsi -ScriptBlock {
         @{ Name = '--timeout'; Description = 'Timeout duration (e.g., 30s, 5m)' }
         @{ Name = '-n'; Description = 'Do not follow symlinks' }
         @{ Name = '--no-follow'; Description = 'Do not follow symlinks' }
+        @{ Name = '--max-depth'; Description = 'Do not follow symlinks beyond N levels of indentation' }
+        @{ Name = '--no-follow-last'; Description = 'Follow symlinks except the last component of each PATH' }
+        @{ Name = '--follow-last'; Description = 'Follow only the symlink of the last component of each PATH' }
         @{ Name = '-l'; Description = 'Output using long format' }
         @{ Name = '--long'; Description = 'Output using long format' }
         @{ Name = '-p'; Description = 'Output file type and permissions' }
         @{ Name = '--permissions'; Description = 'Output file type and permissions' }
+        @{ Name = '--mode-style'; Description = 'Permissions style: symbolic, octal, both' }
+        @{ Name = '-a'; Description = 'Output inode attribute flags (lsattr)' }
+        @{ Name = '--attrs'; Description = 'Output inode attribute flags (lsattr)' }
+        @{ Name = '-N'; Description = 'Output hard link count' }
+        @{ Name = '--nlink'; Description = 'Output hard link count' }
         @{ Name = '-u'; Description = 'Output file owner' }
         @{ Name = '--user'; Description = 'Output file owner' }
         @{ Name = '-g'; Description = 'Output file group' }
         @{ Name = '--group'; Description = 'Output file group' }
         @{ Name = '-s'; Description = 'Output file size (bytes)' }
         @{ Name = '--size'; Description = 'Output file size (bytes)' }
+        @{ Name = '-b'; Description = 'Output allocated size (bytes)' }
+        @{ Name = '--blocks'; Description = 'Output allocated size (bytes)' }
+        @{ Name = '-B'; Description = 'Output preferred I/O block size (bytes)' }
+        @{ Name = '--blksize'; Description = 'Output preferred I/O block size (bytes)' }
+        @{ Name = '-H'; Description = 'Output sizes in powers of 1024 (KiB, MiB)' }
+        @{ Name = '--human'; Description = 'Output sizes in powers of 1024 (KiB, MiB)' }
+        @{ Name = '--si'; Description = 'Output sizes in powers of 1000 (kB, MB)' }
         @{ Name = '-i'; Description = 'Output file inode' }
         @{ Name = '--inode'; Description = 'Output file inode' }
         @{ Name = '-m'; Description = 'Output mount point symbols' }
         @{ Name = '--mount'; Description = 'Output mount point symbols' }
+        @{ Name = '-c'; Description = 'Output file capabilities' }
+        @{ Name = '--caps'; Description = 'Output file capabilities' }
+        @{ Name = '-d'; Description = 'Output device node name and driver' }
+        @{ Name = '--device'; Description = 'Output device node name and driver' }
+        @{ Name = '-T'; Description = 'Output content type of the final target' }
+        @{ Name = '--type'; Description = 'Output content type of the final target' }
+        @{ Name = '--digest'; Description = 'Output digest of the final target' }
+        @{ Name = '--format'; Description = 'Output format' }
+        @{ Name = '--as'; Description = 'Simulate access as USER[:GROUP]' }
+        @{ Name = '--audit'; Description = 'Report entries an untrusted user could modify' }
+        @{ Name = '--trust'; Description = 'Trust USER[,USER...] in addition to root' }
+        @{ Name = '--policy'; Description = 'Policy FILE of rules evaluated by check' }
+        @{ Name = '--beneath'; Description = 'Report symlinks in DIR resolving outside it' }
+        @{ Name = '--race'; Description = 'Walk each PATH N times and report entries that changed' }
+        @{ Name = '--concurrent'; Description = 'Run the walks of --race concurrently' }
+        @{ Name = '--watch'; Description = 'Print a diff each time a PATH resolves differently' }
+        @{ Name = '--on-change'; Description = 'Run CMD when a watched target or link changes (implies --watch)' }
+        @{ Name = '--jobs'; Description = 'Resolve up to N symlinks at once in scan' }
+        @{ Name = '--max-hops'; Description = 'Report chains of more than N links in scan (default 8)' }
+        @{ Name = '--in'; Description = 'Search DIR[,DIR...] for refs (default /)' }
+        @{ Name = '--exclude'; Description = 'Skip PATTERN[,PATTERN...] in scan and refs' }
+        @{ Name = '--one-file-system'; Description = 'Stay on the filesystem of each DIR in scan and refs' }
+        @{ Name = '--tree'; Description = 'Merge the chains of all PATHs into one tree' }
+        @{ Name = '--absolute'; Description = 'Output absolute link targets and the canonical path of each entry' }
+        @{ Name = '--links-only'; Description = 'Output only symlinks, mount points, errors and the endpoint' }
+        @{ Name = '-f'; Description = 'Print only the canonical path; all but the last component must exist' }
+        @{ Name = '--canonicalize'; Description = 'Print only the canonical path; all but the last component must exist' }
+        @{ Name = '-e'; Description = 'Print only the canonical path; all components must exist' }
+        @{ Name = '--canonicalize-existing'; Description = 'Print only the canonical path; all components must exist' }
+        @{ Name = '--canonicalize-missing'; Description = 'Print only the canonical path; no component need exist (-m is --mount)' }
+        @{ Name = '-z'; Description = 'End each canonical path with NUL, not newline' }
+        @{ Name = '--zero'; Description = 'End each canonical path with NUL, not newline' }
     )
     
     # Check if completing a timeout value
@@ -220,6 +357,33 @@ Register-ArgumentCompleter -CommandName lsi -ScriptBlock {
         return
     }
     
+    # Check if completing a mode-style value
+    if ($prevWord -eq '--mode-style') {
+        $styles = @('symbolic', 'octal', 'both')
+        $styles | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+            [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+        }
+        return
+    }
+    
+    # Check if completing a digest value
+    if ($prevWord -eq '--digest') {
+        $algorithms = @('sha256', 'sha512', 'blake2b')
+        $algorithms | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+            [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+        }
+        return
+    }
+    
+    # Check if completing a format value
+    if ($prevWord -eq '--format') {
+        $formats = @('text', 'json', 'sarif')
+        $formats | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+            [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+        }
+        return
+    }
+    
     # Complete flags
     if ($wordToComplete -match '^-') {
         $flags | Where-Object { $_.Name -like "$wordToComplete*" } | ForEach-Object {
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+)
+
+// diffCommand is the subcommand comparing the resolution of two paths.
+const diffCommand = "diff"
+
+// errTargetsDiffer is returned by diff when the paths resolve to different
+// files, so that it exits non-zero as cmp(1) does.
+var errTargetsDiffer = errors.New("targets differ")
+
+// chainDiff aligns two resolution chains by the entries they share at either
+// end.
+type chainDiff struct {
+	a, b []entry
+
+	// prefix and suffix count the leading and trailing entries shared.
+	prefix, suffix int
+}
+
+// diffReport is the structured output produced by diff.
+type diffReport struct {
+	A          pathReport `json:"a"`
+	B          pathReport `json:"b"`
+	Prefix     int        `json:"prefix"`
+	Suffix     int        `json:"suffix"`
+	SameTarget bool       `json:"sameTarget"`
+}
+
+// sameFile reports whether two entries resolved to the same file, and are
+// the same symlink if either is one.
+func sameFile(x, y entry) bool {
+	return x.Err == nil && y.Err == nil && x.Dev == y.Dev && x.Inode == y.Inode && x.Link == y.Link
+}
+
+// alignChains finds the entries shared at the start and end of two chains.
+// Since entries are compared as files rather than by name, paths in
+// different roots align where they reach the same files.
+func alignChains(a, b []entry) chainDiff {
+	d := chainDiff{a: a, b: b}
+	n := min(len(a), len(b))
+	for d.prefix < n && sameFile(a[d.prefix], b[d.prefix]) {
+		d.prefix++
+	}
+	for d.suffix < n-d.prefix && sameFile(a[len(a)-1-d.suffix], b[len(b)-1-d.suffix]) {
+		d.suffix++
+	}
+	return d
+}
+
+// sameTarget reports whether both chains end at the same file.
+func (d chainDiff) sameTarget() bool {
+	return len(d.a) > 0 && len(d.b) > 0 && sameFile(d.a[len(d.a)-1], d.b[len(d.b)-1])
+}
+
+// printDiff walks both paths and prints how their chains align, either as
+// the shared and differing entries followed by a summary, or as JSON. A walk
+// ending at an entry that cannot be resolved is compared rather than treated
+// as an error.
+func printDiff(ctx context.Context, out io.Writer, pathA, pathB string, opts options) error {
+	if opts.format == formatSARIF {
+		return fmt.Errorf("%s: --format %s is not supported", diffCommand, opts.format)
+	}
+
+	var chains [2][]entry
+	for i, p := range []string{pathA, pathB} {
+		chains[i], _ = collectEntries(ctx, p, opts)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	d := alignChains(chains[0], chains[1])
+
+	switch opts.format {
+	case formatJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err := enc.Encode(diffReport{
+			A:          pathReport{Path: filepath.Clean(pathA), Entries: d.a},
+			B:          pathReport{Path: filepath.Clean(pathB), Entries: d.b},
+			Prefix:     d.prefix,
+			Suffix:     d.suffix,
+			SameTarget: d.sameTarget(),
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		d.print(out, opts)
+	}
+
+	if !d.sameTarget() {
+		return errTargetsDiffer
+	}
+	return nil
+}
+
+// print prints the shared leading entries, the differing entries of each
+// chain marked "<" and ">" as by diff(1), and the shared trailing entries,
+// followed by where the chains diverge and converge.
+func (d chainDiff) print(w io.Writer, opts options) {
+	widths := calculateWidths(slices.Concat(d.a, d.b), opts)
+	for _, part := range []struct {
+		mark    string
+		entries []entry
+	}{
+		{" ", d.a[:d.prefix]},
+		{"<", d.a[d.prefix : len(d.a)-d.suffix]},
+		{">", d.b[d.prefix : len(d.b)-d.suffix]},
+		{" ", d.a[len(d.a)-d.suffix:]},
+	} {
+		for _, e := range part.entries {
+			printDiffLine(w, part.mark, e, opts, widths)
+		}
+	}
+
+	fmt.Fprintln(w)
+	if d.prefix == len(d.a) && d.prefix == len(d.b) {
+		fmt.Fprintln(w, "diverge:  never")
+	} else {
+		at := fmt.Sprintf("at %s and %s", d.dest(d.a, d.prefix), d.dest(d.b, d.prefix))
+		if d.prefix > 0 {
+			at = fmt.Sprintf("after %s %s", d.a[d.prefix-1].Dest, at)
+		}
+		fmt.Fprintln(w, "diverge: ", at)
+		if d.suffix > 0 {
+			at := d.dest(d.a, len(d.a)-d.suffix)
+			if b := d.dest(d.b, len(d.b)-d.suffix); b != at {
+				at += " and " + b
+			}
+			fmt.Fprintln(w, "converge: at", at)
+		} else {
+			fmt.Fprintln(w, "converge: never")
+		}
+	}
+
+	a, b := d.a[len(d.a)-1], d.b[len(d.b)-1]
+	if d.sameTarget() {
+		fmt.Fprintf(w, "target:   same (inode %d on device %d)\n", a.Inode, a.Dev)
+	} else {
+		fmt.Fprintf(w, "target:   different (%s and %s)\n", describeTarget(a), describeTarget(b))
+	}
+}
+
+// dest returns the path of the i-th entry of a chain, or "(end)" if the
+// chain ended before it.
+func (d chainDiff) dest(chain []entry, i int) string {
+	if i >= len(chain) {
+		return "(end)"
+	}
+	return chain[i].Dest
+}
+
+// describeTarget identifies the final entry of a chain.
+func describeTarget(e entry) string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Dest, unwrapPathError(e.Err))
+	}
+	return fmt.Sprintf("inode %d on device %d", e.Inode, e.Dev)
+}
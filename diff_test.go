@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestAlignChains tests that chains are aligned by the files shared at their
+// start and end.
+func TestAlignChains(t *testing.T) {
+	file := func(dest string, inode uint64, link string) entry {
+		return entry{Dest: dest, Dev: 1, Inode: inode, Link: link}
+	}
+	root, usr := file("/", 2, ""), file("/usr", 3, "")
+	lib, py := file("/usr/lib", 4, ""), file("/usr/lib/py3", 5, "")
+	missing := entry{Dest: "/usr/lib/py4", Err: errors.New("no such file or directory")}
+
+	tests := []struct {
+		name           string
+		a, b           []entry
+		prefix, suffix int
+		same           bool
+	}{
+		{
+			name:   "identical",
+			a:      []entry{root, usr, lib, py},
+			b:      []entry{root, usr, lib, py},
+			prefix: 4, suffix: 0, same: true,
+		},
+		{
+			name:   "different roots",
+			a:      []entry{file("/srv/a", 6, ""), usr, lib, py},
+			b:      []entry{file("/srv/b", 7, ""), file("/srv/b/usr", 8, ""), file("/srv/b/usr/lib", 9, "/usr/lib"), root, usr, lib, py},
+			prefix: 0, suffix: 3, same: true,
+		},
+		{
+			name:   "different links",
+			a:      []entry{root, usr, file("/usr/python", 10, "lib/py3"), lib, py},
+			b:      []entry{root, usr, file("/usr/python", 10, "lib/py4"), lib, missing},
+			prefix: 2, suffix: 0, same: false,
+		},
+		{
+			name:   "prefix of the other",
+			a:      []entry{root, usr},
+			b:      []entry{root, usr, lib},
+			prefix: 2, suffix: 0, same: false,
+		},
+		{
+			name:   "both fail",
+			a:      []entry{root, usr, lib, missing},
+			b:      []entry{root, usr, lib, missing},
+			prefix: 3, suffix: 0, same: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := alignChains(tt.a, tt.b)
+			if d.prefix != tt.prefix || d.suffix != tt.suffix {
+				t.Errorf("alignChains() prefix, suffix = %d, %d, want %d, %d", d.prefix, d.suffix, tt.prefix, tt.suffix)
+			}
+			if got := d.sameTarget(); got != tt.same {
+				t.Errorf("sameTarget() = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Digest algorithms accepted by --digest.
+const (
+	digestSHA256  = "sha256"
+	digestSHA512  = "sha512"
+	digestBLAKE2b = "blake2b"
+)
+
+// digestChunkSize is the number of bytes hashed between cancellation checks.
+const digestChunkSize = 1 << 20
+
+// newDigest returns a hash implementing the named algorithm.
+func newDigest(algo string) (hash.Hash, error) {
+	switch algo {
+	case digestSHA256:
+		return sha256.New(), nil
+	case digestSHA512:
+		return sha512.New(), nil
+	case digestBLAKE2b:
+		return blake2b.New512(nil)
+	default:
+		return nil, fmt.Errorf("unsupported digest: %s", algo)
+	}
+}
+
+// digestFile hashes the content of the file at dest and returns it in the
+// form "algo:hex". The context is checked between chunks so that hashing a
+// large file honours the timeout.
+func digestFile(ctx context.Context, dest, algo string) (string, error) {
+	h, err := newDigest(algo)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(dest)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	for {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		n, err := io.CopyN(h, f, digestChunkSize)
+		if err == io.EOF || (err == nil && n < digestChunkSize) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return algo + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDigestFile tests each supported digest algorithm.
+func TestDigestFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "hello")
+	if err := os.WriteFile(file, []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	tests := []struct {
+		algo string
+		want string
+	}{
+		{digestSHA256, "sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+		{digestSHA512, "sha512:e7c22b994c59d9cf2b48e549b1e24666636045930d3da7c1acb299d1c3b7f931f94aae41edda2c2b207a36e10f8bcb8d45223e54878f5b316e7ce3b6bc019629"},
+		{digestBLAKE2b, "blake2b:f60ce482e5cc1229f39d71313171a8d9f4ca3a87d066bf4b205effb528192a75f14f3271e2c1a90e1de53f275b4d4793eef2f5e31ea90d2ce29d2e481c36435f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algo, func(t *testing.T) {
+			got, err := digestFile(context.Background(), file, tt.algo)
+			if err != nil {
+				t.Fatalf("digestFile() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("digestFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDigestFileLarge tests hashing a file spanning several chunks.
+func TestDigestFileLarge(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "large")
+	if err := os.WriteFile(file, make([]byte, 3*digestChunkSize+1), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	exact := filepath.Join(tmpDir, "exact")
+	if err := os.WriteFile(exact, make([]byte, 2*digestChunkSize), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	a, err := digestFile(context.Background(), file, digestSHA256)
+	if err != nil {
+		t.Fatalf("digestFile() error = %v", err)
+	}
+	b, err := digestFile(context.Background(), exact, digestSHA256)
+	if err != nil {
+		t.Fatalf("digestFile() error = %v", err)
+	}
+	if a == b {
+		t.Errorf("digestFile() returned %q for files of different length", a)
+	}
+}
+
+// TestDigestFileCanceled tests that hashing honours context cancellation.
+func TestDigestFileCanceled(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "file")
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := digestFile(ctx, file, digestSHA256); err != context.Canceled {
+		t.Errorf("digestFile() with canceled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// TestDigestFileErrors tests unsupported algorithms and missing files.
+func TestDigestFileErrors(t *testing.T) {
+	if _, err := digestFile(context.Background(), os.DevNull, "md5"); err == nil {
+		t.Error("digestFile() with unsupported algorithm error = nil, want error")
+	}
+	if _, err := digestFile(context.Background(), "/nonexistent/file", digestSHA256); err == nil {
+		t.Error("digestFile() with missing file error = nil, want error")
+	}
+}
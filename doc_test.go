@@ -137,7 +137,7 @@ func Example_calculateWidths() {
 		{Mode: "drwxr-xr-x", User: "admin", Group: "staff", Size: 4096, Inode: 67890},
 	}
 
-	w := calculateWidths(entries)
+	w := calculateWidths(entries, options{})
 
 	fmt.Printf("Mode width: %d\n", w.mode)
 	fmt.Printf("User width: %d\n", w.user)
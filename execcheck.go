@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// execCheckCommand is the subcommand reporting why a path cannot be executed.
+const execCheckCommand = "exec-check"
+
+// maxInterpreterDepth is the number of nested script interpreters the kernel
+// follows before execve fails with ELOOP (BINPRM_MAX_RECURSION).
+const maxInterpreterDepth = 4
+
+// Rules reported by exec-check.
+const (
+	ruleExecSearch      = "exec-search"
+	ruleExecFollow      = "exec-follow"
+	ruleExecType        = "exec-type"
+	ruleExecPermission  = "exec-permission"
+	ruleExecNoexec      = "exec-noexec"
+	ruleExecInterpreter = "exec-interpreter"
+)
+
+// resolveExec walks a path and attaches a finding to each entry that would
+// cause execve to fail for the identity given with --as, or the current user.
+func resolveExec(ctx context.Context, path string, opts options) ([]entry, error) {
+	id := currentIdentity()
+	if opts.as != "" {
+		var err error
+		if id, err = lookupIdentity(opts.as); err != nil {
+			return nil, err
+		}
+	}
+
+	// Access is evaluated for execution rather than reading, so the walk
+	// itself must not evaluate it. Execution always follows links.
+	opts.as, opts.noFollow = "", false
+	opts.maxDepth, opts.noFollowLast, opts.followLast = 0, false, false
+	return checkExec(ctx, path, opts, id, 0)
+}
+
+// checkExec resolves path and records the reasons it cannot be executed as
+// id. Script interpreters are checked recursively up to the kernel limit. A
+// walk denied permission to look up an entry is reported as a finding on the
+// last directory it reached, rather than as an error.
+func checkExec(ctx context.Context, path string, opts options, id identity, depth int) ([]entry, error) {
+	entries, err := resolvePath(ctx, path, opts)
+	if len(entries) == 0 || err != nil && !errors.Is(err, fs.ErrPermission) {
+		return entries, err
+	}
+
+	last := len(entries) - 1
+	for i := range entries[:last] {
+		e := &entries[i]
+		perm, class := effectivePerm(e, id, entryACL(e))
+		if e.Link != "" {
+			e.Access = &access{Perm: fmtPerm(perm), Need: "follow"}
+			if reason, ok := mayFollowLink(e, id); !ok {
+				e.Access.Denied = true
+				e.Findings = append(e.Findings, newFinding(ruleExecFollow, reason))
+			}
+			continue
+		}
+		e.Access = &access{Perm: fmtPerm(perm), Need: "search"}
+		if 0 == perm&permExecute {
+			e.Access.Denied = true
+			e.Findings = append(e.Findings, newFinding(ruleExecSearch,
+				fmt.Sprintf("no search permission (%s as %s: %s)", id.Name, class, e.Access.Perm)))
+		}
+	}
+	if err != nil {
+		return entries, deniedSearch(entries, err)
+	}
+
+	e := &entries[last]
+	if !e.Info.Mode().IsRegular() {
+		e.Findings = append(e.Findings, newFinding(ruleExecType,
+			"not a regular file ("+describeMode(e.Info.Mode())+")"))
+		return entries, nil
+	}
+
+	perm, class := effectivePerm(e, id, entryACL(e))
+	e.Access = &access{Perm: fmtPerm(perm), Need: "execute"}
+	if 0 == perm&permExecute {
+		e.Access.Denied = true
+		e.Findings = append(e.Findings, newFinding(ruleExecPermission,
+			fmt.Sprintf("no execute permission (%s as %s: %s)", id.Name, class, e.Access.Perm)))
+	}
+	if i := firstDenied(entries); i >= 0 {
+		entries[i].Access.first = true
+	}
+
+	if isNoexec(e.Dest) {
+		m := &entries[mountEntry(entries, last)]
+		m.Findings = append(m.Findings, newFinding(ruleExecNoexec,
+			"filesystem containing "+e.Name+" is mounted noexec"))
+	}
+
+	found, err := checkInterpreter(ctx, e, opts, id, depth)
+	if err != nil {
+		return nil, err
+	}
+	e.Findings = append(e.Findings, found...)
+
+	return entries, nil
+}
+
+// checkInterpreter reports the findings of the interpreter named by the
+// shebang line of e, if it has one. Only cancellation is returned as an error.
+func checkInterpreter(ctx context.Context, e *entry, opts options, id identity, depth int) ([]finding, error) {
+	f, err := os.Open(e.Dest)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLength)
+	n, _ := io.ReadFull(f, head)
+	interp, _, ok := parseShebang(head[:n])
+	if !ok {
+		return nil, nil
+	}
+
+	prefix := "interpreter " + interp + ": "
+	if depth+1 >= maxInterpreterDepth {
+		return []finding{newFinding(ruleExecInterpreter, prefix+"too many levels of interpreters")}, nil
+	}
+
+	entries, err := checkExec(ctx, interp, opts, id, depth+1)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return []finding{newFinding(ruleExecInterpreter, prefix+unwrapPathError(err).Error())}, nil
+	}
+
+	var found []finding
+	for _, ie := range entries {
+		for _, x := range ie.Findings {
+			found = append(found, newFinding(ruleExecInterpreter, prefix+ie.Name+": "+x.Message))
+		}
+	}
+	return found, nil
+}
+
+// deniedSearch attributes err, a permission error from the lookup of the
+// last entry, to the directory that lookup searched: the nearest preceding
+// entry that is not a link. If the search was already reported as denied, or
+// no such directory was walked, err is returned instead.
+func deniedSearch(entries []entry, err error) error {
+	i := len(entries) - 2
+	for i >= 0 && entries[i].Link != "" {
+		i--
+	}
+	if i < 0 {
+		return err
+	}
+
+	d := &entries[i]
+	if !d.Access.Denied {
+		d.Access.Denied = true
+		d.Findings = append(d.Findings, newFinding(ruleExecSearch,
+			"no search permission ("+unwrapPathError(err).Error()+")"))
+	}
+	if j := firstDenied(entries); j >= 0 {
+		entries[j].Access.first = true
+	}
+	return nil
+}
+
+// mountEntry returns the index of the mount point through which the entry at
+// index i was reached, or i itself if no mount point was visited.
+func mountEntry(entries []entry, i int) int {
+	for j := i; j >= 0; j-- {
+		if entries[j].Dev == entries[i].Dev && entries[j].Dev != entries[j].Pdev {
+			return j
+		}
+	}
+	return i
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCheckExec tests the reasons reported for paths that cannot be executed.
+func TestCheckExec(t *testing.T) {
+	dir := t.TempDir()
+	nobody := identity{Name: "nobody", Uid: 65534, Gid: 65534}
+
+	// Temporary directories are private to their owner.
+	for _, d := range []string{filepath.Dir(dir), dir} {
+		if err := os.Chmod(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write := func(name, content string, perm os.FileMode) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), perm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(p, perm); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	priv := filepath.Join(dir, "priv")
+	if err := os.Mkdir(priv, 0700); err != nil {
+		t.Fatal(err)
+	}
+	hidden := write(filepath.Join("priv", "tool"), "\x7fELF", 0755)
+
+	tests := []struct {
+		name     string
+		path     string
+		id       identity
+		wantRule []string
+		wantMsg  string
+	}{
+		{
+			name: "executable",
+			path: write("tool", "\x7fELF", 0755),
+			id:   nobody,
+		},
+		{
+			name:     "missing execute permission",
+			path:     write("data", "data", 0644),
+			id:       nobody,
+			wantRule: []string{ruleExecPermission},
+			wantMsg:  "nobody as other: r--",
+		},
+		{
+			name:     "not a regular file",
+			path:     dir,
+			id:       nobody,
+			wantRule: []string{ruleExecType},
+			wantMsg:  "not a regular file (directory)",
+		},
+		{
+			name:     "unsearchable directory",
+			path:     hidden,
+			id:       nobody,
+			wantRule: []string{ruleExecSearch},
+			wantMsg:  "no search permission",
+		},
+		{
+			name:     "missing interpreter",
+			path:     write("script", "#!"+filepath.Join(dir, "nope")+" -x\n", 0755),
+			id:       nobody,
+			wantRule: []string{ruleExecInterpreter},
+			wantMsg:  "no such file or directory",
+		},
+		{
+			name:     "non-executable interpreter",
+			path:     write("script2", "#!"+filepath.Join(dir, "data")+"\n", 0755),
+			id:       nobody,
+			wantRule: []string{ruleExecInterpreter},
+			wantMsg:  "no execute permission",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := checkExec(context.Background(), tt.path, options{}, tt.id, 0)
+			if err != nil {
+				t.Fatalf("checkExec() error = %v", err)
+			}
+
+			var rules []string
+			var msgs []string
+			for _, e := range entries {
+				for _, f := range e.Findings {
+					rules = append(rules, f.Rule)
+					msgs = append(msgs, f.Message)
+				}
+			}
+			if strings.Join(rules, ",") != strings.Join(tt.wantRule, ",") {
+				t.Fatalf("checkExec() rules = %v, want %v", rules, tt.wantRule)
+			}
+			if tt.wantMsg != "" && !strings.Contains(strings.Join(msgs, "\n"), tt.wantMsg) {
+				t.Errorf("checkExec() messages = %q, want to contain %q", msgs, tt.wantMsg)
+			}
+		})
+	}
+}
+
+// TestCheckExecRecursion tests that nested interpreters are limited.
+func TestCheckExecRecursion(t *testing.T) {
+	dir := t.TempDir()
+	loop := filepath.Join(dir, "loop")
+	if err := os.WriteFile(loop, []byte("#!"+loop+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := checkExec(context.Background(), loop, options{}, identity{Name: "root"}, 0)
+	if err != nil {
+		t.Fatalf("checkExec() error = %v", err)
+	}
+	e := entries[len(entries)-1]
+	if len(e.Findings) == 0 || !strings.Contains(e.Findings[0].Message, "too many levels of interpreters") {
+		t.Errorf("checkExec() findings = %v, want interpreter recursion limit", e.Findings)
+	}
+}
+
+// TestMountEntry tests locating the mount point that contains an entry.
+func TestMountEntry(t *testing.T) {
+	entries := []entry{
+		{Name: "/", Dev: 1, Pdev: 1},
+		{Name: "mnt", Dev: 2, Pdev: 1},
+		{Name: "sub", Dev: 2, Pdev: 2},
+		{Name: "file", Dev: 2, Pdev: 2},
+	}
+	if got := mountEntry(entries, 3); got != 1 {
+		t.Errorf("mountEntry() = %d, want 1", got)
+	}
+	if got := mountEntry(entries[:1], 0); got != 0 {
+		t.Errorf("mountEntry() = %d, want 0", got)
+	}
+}
@@ -0,0 +1,79 @@
+//go:build unix
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// execCheckEnv names the path a re-executed test binary checks as nobody.
+const execCheckEnv = "LSI_TEST_EXEC_CHECK"
+
+// TestRunExecCheckUnsearchable tests that a directory the walk itself cannot
+// search is reported as a finding rather than an error. The check runs in a
+// copy of the test binary with the privileges of nobody, so it needs root.
+func TestRunExecCheckUnsearchable(t *testing.T) {
+	if path := os.Getenv(execCheckEnv); path != "" {
+		var out bytes.Buffer
+		err := run(context.Background(), &out, io.Discard, []string{"exec-check", path})
+		fmt.Printf("%serror: %v\n", out.String(), err)
+		return
+	}
+	if os.Geteuid() != 0 {
+		t.Skip("dropping privileges requires root")
+	}
+
+	dir := t.TempDir()
+	for _, d := range []string{filepath.Dir(dir), dir} {
+		if err := os.Chmod(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bin := filepath.Join(dir, "bin")
+	if err := os.MkdirAll(filepath.Join(dir, "locked", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "locked", "bin", "t"), []byte("\x7fELF"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(dir, "locked"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	// The test binary lives in a directory private to root.
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(self)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bin, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(bin, "-test.run=^TestRunExecCheckUnsearchable$")
+	cmd.Env = append(os.Environ(), execCheckEnv+"="+filepath.Join(dir, "locked", "bin", "t"))
+	cmd.SysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{Uid: 65534, Gid: 65534}}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", bin, err, out)
+	}
+
+	if !strings.Contains(string(out), "locked [exec-search: no search permission") {
+		t.Errorf("output = %q, want exec-search finding on locked", out)
+	}
+	if !strings.Contains(string(out), "error: 1 finding") {
+		t.Errorf("output = %q, want 1 finding", out)
+	}
+}
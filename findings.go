@@ -0,0 +1,67 @@
+package main
+
+// Severity levels of findings, named as in SARIF.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityNote    = "note"
+)
+
+// finding describes a problem detected at a single entry of a resolved path.
+// Findings are attached to the entry responsible for them.
+type finding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ruleInfo describes a rule that findings are reported against.
+type ruleInfo struct {
+	Description string
+	Severity    string
+}
+
+// builtinRules describes the rules checked by exec-check, verify, scan,
+// --audit, --beneath and --race.
+var builtinRules = map[string]ruleInfo{
+	ruleExecSearch:      {"Directory is not searchable", severityError},
+	ruleExecFollow:      {"Symbolic link cannot be followed", severityError},
+	ruleExecType:        {"Target is not a regular file", severityError},
+	ruleExecPermission:  {"Target is not executable", severityError},
+	ruleExecNoexec:      {"Target is on a noexec mount", severityError},
+	ruleExecInterpreter: {"Script interpreter cannot be executed", severityError},
+	ruleAuditWritable:   {"Writable by an untrusted user", severityError},
+	ruleAuditOwner:      {"Owned by an untrusted user", severityWarning},
+	ruleAuditSymlink:    {"Symbolic link controlled by an untrusted user", severityError},
+	ruleBeneathEscape:   {"Symbolic link resolves outside the tree", severityError},
+	ruleDriftChain:      {"Resolution differs from the baseline", severityError},
+	ruleDriftLink:       {"Symbolic link was retargeted", severityError},
+	ruleDriftInode:      {"Entry was replaced", severityError},
+	ruleDriftDevice:     {"Entry moved to another device", severityError},
+	ruleDriftMount:      {"Mount point was added or removed", severityError},
+	ruleDriftOwner:      {"Owner or group changed", severityError},
+	ruleDriftMode:       {"Permissions changed", severityWarning},
+	ruleRaceChange:      {"Identity changed between walks", severityError},
+	ruleScanDangling:    {"Symbolic link does not resolve", severityError},
+	ruleScanLoop:        {"Symbolic link resolves in a loop", severityError},
+	ruleScanLongChain:   {"Symbolic link resolves through too many links", severityWarning},
+	ruleScanCrossMount:  {"Symbolic link resolves onto another filesystem", severityNote},
+}
+
+// newFinding returns a finding of a built-in rule with its default severity.
+func newFinding(rule, message string) finding {
+	return finding{Rule: rule, Severity: builtinRules[rule].Severity, Message: message}
+}
+
+// String returns the finding as it is annotated on its entry.
+func (f finding) String() string {
+	return f.Rule + ": " + f.Message
+}
+
+// countFindings returns the total number of findings attached to entries.
+func countFindings(entries []entry) (n int) {
+	for _, e := range entries {
+		n += len(e.Findings)
+	}
+	return n
+}
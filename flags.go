@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"io"
 	"runtime/debug"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/integrii/flaggy"
@@ -18,20 +20,68 @@ const (
 
 	// defaultTimeout is the default duration before canceling path traversal.
 	defaultTimeout = 0
+
+	// Output formats accepted by --format.
+	formatText  = "text"
+	formatJSON  = "json"
+	formatSARIF = "sarif"
+
+	// Permission display styles accepted by --mode-style.
+	modeStyleSymbolic = "symbolic"
+	modeStyleOctal    = "octal"
+	modeStyleBoth     = "both"
 )
 
 // options holds all command-line flag values.
 type options struct {
-	version  bool
-	timeout  time.Duration
-	noFollow bool
-	long     bool
-	mode     bool
-	user     bool
-	group    bool
-	size     bool
-	inode    bool
-	mount    bool
+	version    bool
+	timeout    time.Duration
+	noFollow   bool
+	long       bool
+	mode       bool
+	modeStyle  string
+	attrs      bool
+	nlink      bool
+	user       bool
+	group      bool
+	size       bool
+	blocks     bool
+	blksize    bool
+	human      bool
+	si         bool
+	inode      bool
+	mount      bool
+	caps       bool
+	device     bool
+	fileType   bool
+	digest     string
+	format     string
+	as         string
+	audit      bool
+	trust      string
+	policy     string
+	beneath    string
+	race       int
+	concurrent bool
+	watch      bool
+	onChange   string
+	jobs       int
+	maxHops    int
+	in         string
+	exclude    string
+	oneFileSys bool
+	tree       bool
+	absolute   bool
+	linksOnly  bool
+
+	maxDepth     int
+	noFollowLast bool
+	followLast   bool
+
+	canonicalize         bool
+	canonicalizeExisting bool
+	canonicalizeMissing  bool
+	zero                 bool
 }
 
 // parseFlags parses command-line arguments and returns options and remaining paths.
@@ -67,13 +117,49 @@ func parseFlags(args []string) (opts options, paths []string, err error) {
 	parser.Bool(&opts.version, "v", "version", "Display version information")
 	parser.Duration(&opts.timeout, "t", "timeout", "Timeout duration (e.g., 30s, 5m)")
 	parser.Bool(&opts.noFollow, "n", "no-follow", "Do not follow symlinks")
-	parser.Bool(&opts.long, "l", "long", "Output using long format (-p -u -g -s -m)")
+	parser.Int(&opts.maxDepth, "", "max-depth", "Do not follow symlinks beyond N levels of indentation")
+	parser.Bool(&opts.noFollowLast, "", "no-follow-last", "Follow symlinks except the last component of each PATH")
+	parser.Bool(&opts.followLast, "", "follow-last", "Follow only the symlink of the last component of each PATH")
+	parser.Bool(&opts.long, "l", "long", "Output using long format (-p -u -g -s -m -c)")
 	parser.Bool(&opts.mode, "p", "permissions", "Output file type and permissions")
+	parser.String(&opts.modeStyle, "", "mode-style", "Permissions style: symbolic, octal, both")
+	parser.Bool(&opts.attrs, "a", "attrs", "Output inode attribute flags (lsattr)")
+	parser.Bool(&opts.nlink, "N", "nlink", "Output hard link count")
 	parser.Bool(&opts.user, "u", "user", "Output file owner")
 	parser.Bool(&opts.group, "g", "group", "Output file group")
-	parser.Bool(&opts.size, "s", "size", "Output file size (bytes)")
+	parser.Bool(&opts.size, "s", "size", "Output file size (bytes, or device number)")
+	parser.Bool(&opts.blocks, "b", "blocks", "Output allocated size (bytes)")
+	parser.Bool(&opts.blksize, "B", "blksize", "Output preferred I/O block size (bytes)")
+	parser.Bool(&opts.human, "H", "human", "Output sizes in powers of 1024 (KiB, MiB)")
+	parser.Bool(&opts.si, "", "si", "Output sizes in powers of 1000 (kB, MB)")
 	parser.Bool(&opts.inode, "i", "inode", "Output file inode")
 	parser.Bool(&opts.mount, "m", "mount", "Output mount point symbols ("+mountPointSymbol+")")
+	parser.Bool(&opts.caps, "c", "caps", "Output file capabilities")
+	parser.Bool(&opts.device, "d", "device", "Output device node name and driver")
+	parser.Bool(&opts.fileType, "T", "type", "Output content type of the final target")
+	parser.String(&opts.digest, "", "digest", "Output digest of the final target: sha256, sha512, blake2b")
+	parser.String(&opts.format, "", "format", "Output format: text, json, sarif")
+	parser.String(&opts.as, "", "as", "Simulate access as USER[:GROUP]")
+	parser.Bool(&opts.audit, "", "audit", "Report entries an untrusted user could modify")
+	parser.String(&opts.trust, "", "trust", "Trust USER[,USER...] in addition to root")
+	parser.String(&opts.policy, "", "policy", "Policy FILE of rules evaluated by check")
+	parser.String(&opts.beneath, "", "beneath", "Report symlinks in DIR resolving outside it")
+	parser.Int(&opts.race, "", "race", "Walk each PATH N times and report entries that changed")
+	parser.Bool(&opts.concurrent, "", "concurrent", "Run the walks of --race concurrently")
+	parser.Bool(&opts.watch, "", "watch", "Print a diff each time a PATH resolves differently")
+	parser.String(&opts.onChange, "", "on-change", "Run CMD when a watched target or link changes (implies --watch)")
+	parser.Int(&opts.jobs, "", "jobs", "Resolve up to N symlinks at once in scan")
+	parser.Int(&opts.maxHops, "", "max-hops", "Report chains of more than N links in scan (default 8)")
+	parser.String(&opts.in, "", "in", "Search DIR[,DIR...] for refs (default /)")
+	parser.String(&opts.exclude, "", "exclude", "Skip PATTERN[,PATTERN...] in scan and refs")
+	parser.Bool(&opts.oneFileSys, "", "one-file-system", "Stay on the filesystem of each DIR in scan and refs")
+	parser.Bool(&opts.tree, "", "tree", "Merge the chains of all PATHs into one tree")
+	parser.Bool(&opts.absolute, "", "absolute", "Output absolute link targets and the canonical path of each entry")
+	parser.Bool(&opts.linksOnly, "", "links-only", "Output only symlinks, mount points, errors and the endpoint")
+	parser.Bool(&opts.canonicalize, "f", "canonicalize", "Print only the canonical path; all but the last component must exist")
+	parser.Bool(&opts.canonicalizeExisting, "e", "canonicalize-existing", "Print only the canonical path; all components must exist")
+	parser.Bool(&opts.canonicalizeMissing, "", "canonicalize-missing", "Print only the canonical path; no component need exist (-m is --mount)")
+	parser.Bool(&opts.zero, "z", "zero", "End each canonical path with NUL, not newline")
 
 	// Recover from panics that flaggy might trigger for invalid input.
 	defer func() {
@@ -90,9 +176,97 @@ func parseFlags(args []string) (opts options, paths []string, err error) {
 		return options{}, nil, parseErr
 	}
 
+	// Validate flags taking a fixed set of values.
+	for _, c := range []struct {
+		name, value string
+		choices     []string
+	}{
+		{"mode style", opts.modeStyle, []string{modeStyleSymbolic, modeStyleOctal, modeStyleBoth}},
+		{"digest", opts.digest, []string{digestSHA256, digestSHA512, digestBLAKE2b}},
+		{"format", opts.format, []string{formatText, formatJSON, formatSARIF}},
+	} {
+		if c.value != "" && !slices.Contains(c.choices, c.value) {
+			return options{}, nil, fmt.Errorf("invalid %s: %s (supported: %s)",
+				c.name, c.value, strings.Join(c.choices, ", "))
+		}
+	}
+
+	if opts.race < 0 || opts.race == 1 {
+		return options{}, nil, fmt.Errorf("invalid race count: %d (must be at least 2)", opts.race)
+	}
+
+	for _, c := range []struct {
+		name  string
+		value int
+	}{
+		{"jobs", opts.jobs},
+		{"max hops", opts.maxHops},
+		{"max depth", opts.maxDepth},
+	} {
+		if c.value < 0 {
+			return options{}, nil, fmt.Errorf("invalid %s: %d (must not be negative)", c.name, c.value)
+		}
+	}
+
+	var follow []string
+	for _, m := range []struct {
+		name string
+		set  bool
+	}{
+		{"--no-follow", opts.noFollow},
+		{"--no-follow-last", opts.noFollowLast},
+		{"--follow-last", opts.followLast},
+	} {
+		if m.set {
+			follow = append(follow, m.name)
+		}
+	}
+	if len(follow) > 1 {
+		return options{}, nil, fmt.Errorf("%s cannot be combined", strings.Join(follow, " and "))
+	}
+
+	if opts.onChange != "" {
+		opts.watch = true
+	}
+	if opts.watch && opts.format != "" && opts.format != formatText {
+		return options{}, nil, fmt.Errorf("--watch does not support format: %s", opts.format)
+	}
+	if opts.tree && opts.format != "" && opts.format != formatText {
+		return options{}, nil, fmt.Errorf("--tree does not support format: %s", opts.format)
+	}
+	if opts.tree && opts.watch {
+		return options{}, nil, fmt.Errorf("--tree cannot be combined with --watch")
+	}
+	if opts.linksOnly && (opts.tree || opts.watch || (opts.format != "" && opts.format != formatText)) {
+		return options{}, nil, fmt.Errorf("--links-only applies only to the text output of each PATH")
+	}
+
+	var modes []string
+	for _, m := range []struct {
+		name string
+		set  bool
+	}{
+		{"--canonicalize", opts.canonicalize},
+		{"--canonicalize-existing", opts.canonicalizeExisting},
+		{"--canonicalize-missing", opts.canonicalizeMissing},
+	} {
+		if m.set {
+			modes = append(modes, m.name)
+		}
+	}
+	switch {
+	case len(modes) > 1:
+		return options{}, nil, fmt.Errorf("%s cannot be combined", strings.Join(modes, " and "))
+	case len(modes) == 0 && opts.zero:
+		return options{}, nil, fmt.Errorf("--zero requires a canonicalize mode")
+	case len(modes) == 1 && (len(follow) > 0 || opts.maxDepth > 0 || opts.tree || opts.watch || (opts.format != "" && opts.format != formatText)):
+		return options{}, nil, fmt.Errorf("%s prints only the canonical path", modes[0])
+	}
+
 	// Configure the meta-flags.
 	if opts.long {
 		opts.mode, opts.user, opts.group, opts.size, opts.mount = true, true, true, true, true
+		opts.caps = true
 	}
 
 	// Return options and trailing arguments (paths).
@@ -150,20 +324,76 @@ func showHelp(w io.Writer) {
 	fmt.Fprintf(w, "%s - Analyze file paths by traversing and displaying each path component\n\n", command)
 	fmt.Fprintln(w, "Usage:")
 	fmt.Fprintf(w, "  %s [flags] [--] [PATH ...]\n", command)
+	fmt.Fprintf(w, "  %s exec-check [flags] [--] PATH ...\n", command)
+	fmt.Fprintf(w, "  %s check --policy FILE [flags] [--] PATH ...\n", command)
+	fmt.Fprintf(w, "  %s snapshot [flags] [--] PATH ... > BASELINE\n", command)
+	fmt.Fprintf(w, "  %s verify [flags] [--] BASELINE\n", command)
+	fmt.Fprintf(w, "  %s scan [flags] [--] DIR ...\n", command)
+	fmt.Fprintf(w, "  %s refs [--in DIR[,DIR...]] [flags] [--] TARGET\n", command)
+	fmt.Fprintf(w, "  %s diff [flags] [--] PATH_A PATH_B\n", command)
 	fmt.Fprintf(w, "  %s completion [SHELL]\n\n", command)
 	fmt.Fprintln(w, "Flags:")
 	fmt.Fprintln(w, "  -h --help          Display this help message")
 	fmt.Fprintln(w, "  -v --version       Display version information")
 	fmt.Fprintln(w, "  -t --timeout       Timeout duration (e.g., 30s, 5m)")
 	fmt.Fprintln(w, "  -n --no-follow     Do not follow symlinks")
-	fmt.Fprintln(w, "  -l --long          Output using long format (-p -u -g -s -m)")
+	fmt.Fprintln(w, "     --max-depth     Do not follow symlinks beyond N levels of indentation")
+	fmt.Fprintln(w, "     --no-follow-last")
+	fmt.Fprintln(w, "                     Follow symlinks except the last component of each PATH")
+	fmt.Fprintln(w, "     --follow-last   Follow only the symlink of the last component of each PATH")
+	fmt.Fprintln(w, "  -l --long          Output using long format (-p -u -g -s -m -c)")
 	fmt.Fprintln(w, "  -p --permissions   Output file type and permissions")
+	fmt.Fprintln(w, "     --mode-style    Permissions style: symbolic, octal, both")
+	fmt.Fprintln(w, "  -a --attrs         Output inode attribute flags (lsattr)")
+	fmt.Fprintln(w, "  -N --nlink         Output hard link count")
 	fmt.Fprintln(w, "  -u --user          Output file owner")
 	fmt.Fprintln(w, "  -g --group         Output file group")
-	fmt.Fprintln(w, "  -s --size          Output file size (bytes)")
+	fmt.Fprintln(w, "  -s --size          Output file size (bytes, or device number)")
+	fmt.Fprintln(w, "  -b --blocks        Output allocated size (bytes)")
+	fmt.Fprintln(w, "  -B --blksize       Output preferred I/O block size (bytes)")
+	fmt.Fprintln(w, "  -H --human         Output sizes in powers of 1024 (KiB, MiB)")
+	fmt.Fprintln(w, "     --si            Output sizes in powers of 1000 (kB, MB)")
 	fmt.Fprintln(w, "  -i --inode         Output file inode")
-	fmt.Fprintf(w, "  -m --mount         Output mount point symbols (%s)\n\n", mountPointSymbol)
+	fmt.Fprintf(w, "  -m --mount         Output mount point symbols (%s)\n", mountPointSymbol)
+	fmt.Fprintln(w, "  -c --caps          Output file capabilities")
+	fmt.Fprintln(w, "  -d --device        Output device node name and driver")
+	fmt.Fprintln(w, "  -T --type          Output content type of the final target")
+	fmt.Fprintln(w, "     --digest        Output digest of the final target: sha256, sha512, blake2b")
+	fmt.Fprintln(w, "     --format        Output format: text, json, sarif")
+	fmt.Fprintln(w, "     --as            Simulate access as USER[:GROUP]")
+	fmt.Fprintln(w, "     --audit         Report entries an untrusted user could modify")
+	fmt.Fprintln(w, "     --trust         Trust USER[,USER...] in addition to root")
+	fmt.Fprintln(w, "     --policy        Policy FILE of rules evaluated by check")
+	fmt.Fprintln(w, "     --beneath       Report symlinks in DIR resolving outside it")
+	fmt.Fprintln(w, "     --race          Walk each PATH N times and report entries that changed")
+	fmt.Fprintln(w, "     --concurrent    Run the walks of --race concurrently")
+	fmt.Fprintln(w, "     --watch         Print a diff each time a PATH resolves differently")
+	fmt.Fprintln(w, "     --on-change     Run CMD when a watched target or link changes (implies --watch)")
+	fmt.Fprintln(w, "     --jobs          Resolve up to N symlinks at once in scan")
+	fmt.Fprintln(w, "     --max-hops      Report chains of more than N links in scan (default 8)")
+	fmt.Fprintln(w, "     --in            Search DIR[,DIR...] for refs (default /)")
+	fmt.Fprintln(w, "     --exclude       Skip PATTERN[,PATTERN...] in scan and refs")
+	fmt.Fprintln(w, "     --one-file-system")
+	fmt.Fprintln(w, "                     Stay on the filesystem of each DIR in scan and refs")
+	fmt.Fprintln(w, "     --tree          Merge the chains of all PATHs into one tree")
+	fmt.Fprintln(w, "     --absolute      Output absolute link targets and the canonical path of each entry")
+	fmt.Fprintln(w, "     --links-only    Output only symlinks, mount points, errors and the endpoint")
+	fmt.Fprintln(w, "  -f --canonicalize  Print only the canonical path; all but the last component must exist")
+	fmt.Fprintln(w, "  -e --canonicalize-existing")
+	fmt.Fprintln(w, "                     Print only the canonical path; all components must exist")
+	fmt.Fprintln(w, "     --canonicalize-missing")
+	fmt.Fprintln(w, "                     Print only the canonical path; no component need exist (-m is --mount)")
+	fmt.Fprintln(w, "  -z --zero          End each canonical path with NUL, not newline")
+	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Subcommands:")
+	fmt.Fprintln(w, "  exec-check PATH    Report every reason executing PATH would fail")
+	fmt.Fprintln(w, "  check PATH         Evaluate the rules of --policy against each PATH")
+	fmt.Fprintln(w, "  snapshot PATH      Record the resolution of each PATH as JSON")
+	fmt.Fprintln(w, "  verify BASELINE    Report how recorded paths differ from BASELINE")
+	fmt.Fprintln(w, "  scan DIR           Report broken, looping, long and escaping symlinks in DIR")
+	fmt.Fprintln(w, "  refs TARGET        Report symlinks whose resolution passes through TARGET")
+	fmt.Fprintln(w, "                     Exits zero only if none do (unlike grep)")
+	fmt.Fprintln(w, "  diff PATH_A PATH_B Report where the resolutions of two paths diverge")
 	fmt.Fprintln(w, "  completion [SHELL] Generate shell completion script")
 	fmt.Fprintln(w, "                     SHELL: bash, zsh, fish, powershell")
 	fmt.Fprintln(w, "                     If omitted, auto-detects from environment")
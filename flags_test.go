@@ -101,6 +101,7 @@ func TestParseFlags(t *testing.T) {
 				group:   true,
 				size:    true,
 				mount:   true,
+				caps:    true,
 				timeout: 0,
 			},
 			wantPaths: nil,
@@ -116,6 +117,7 @@ func TestParseFlags(t *testing.T) {
 				group:   true,
 				size:    true,
 				mount:   true,
+				caps:    true,
 				timeout: 0,
 			},
 			wantPaths: nil,
@@ -141,6 +143,54 @@ func TestParseFlags(t *testing.T) {
 			wantPaths: nil,
 			wantErr:   false,
 		},
+		{
+			name: "mode style octal",
+			args: []string{"-p", "--mode-style", "octal"},
+			wantOpts: options{
+				mode:      true,
+				modeStyle: modeStyleOctal,
+				timeout:   0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
+		{
+			name: "mode style both with equals",
+			args: []string{"--mode-style=both"},
+			wantOpts: options{
+				modeStyle: modeStyleBoth,
+				timeout:   0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
+		{
+			name:      "mode style invalid",
+			args:      []string{"--mode-style", "hex"},
+			wantOpts:  options{},
+			wantPaths: nil,
+			wantErr:   true,
+		},
+		{
+			name: "attrs short flag",
+			args: []string{"-a"},
+			wantOpts: options{
+				attrs:   true,
+				timeout: 0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
+		{
+			name: "attrs long flag",
+			args: []string{"--attrs"},
+			wantOpts: options{
+				attrs:   true,
+				timeout: 0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
 		{
 			name: "user short flag",
 			args: []string{"-u"},
@@ -241,6 +291,320 @@ func TestParseFlags(t *testing.T) {
 			wantPaths: nil,
 			wantErr:   false,
 		},
+		{
+			name: "caps short flag",
+			args: []string{"-c"},
+			wantOpts: options{
+				caps:    true,
+				timeout: 0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
+		{
+			name: "caps long flag",
+			args: []string{"--caps"},
+			wantOpts: options{
+				caps:    true,
+				timeout: 0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
+		{
+			name: "device short flag",
+			args: []string{"-d"},
+			wantOpts: options{
+				device:  true,
+				timeout: 0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
+		{
+			name: "device long flag",
+			args: []string{"--device"},
+			wantOpts: options{
+				device:  true,
+				timeout: 0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
+		{
+			name: "type long flag",
+			args: []string{"--type"},
+			wantOpts: options{
+				fileType: true,
+				timeout:  0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
+		{
+			name: "digest and format",
+			args: []string{"--digest", "blake2b", "--format=json"},
+			wantOpts: options{
+				digest:  digestBLAKE2b,
+				format:  formatJSON,
+				timeout: 0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
+		{
+			name:      "digest invalid",
+			args:      []string{"--digest", "md5"},
+			wantOpts:  options{},
+			wantPaths: nil,
+			wantErr:   true,
+		},
+		{
+			name:      "format invalid",
+			args:      []string{"--format", "xml"},
+			wantOpts:  options{},
+			wantPaths: nil,
+			wantErr:   true,
+		},
+		{
+			name: "as user and group",
+			args: []string{"--as", "nobody:nogroup", "/tmp"},
+			wantOpts: options{
+				as:      "nobody:nogroup",
+				timeout: 0,
+			},
+			wantPaths: []string{"/tmp"},
+			wantErr:   false,
+		},
+		{
+			name: "audit with trusted users",
+			args: []string{"--audit", "--trust", "alice,bob", "/etc"},
+			wantOpts: options{
+				audit:   true,
+				trust:   "alice,bob",
+				timeout: 0,
+			},
+			wantPaths: []string{"/etc"},
+			wantErr:   false,
+		},
+		{
+			name: "policy file",
+			args: []string{"--policy", "rules.yaml", "/etc/ssl"},
+			wantOpts: options{
+				policy:  "rules.yaml",
+				timeout: 0,
+			},
+			wantPaths: []string{"/etc/ssl"},
+			wantErr:   false,
+		},
+		{
+			name: "sarif format",
+			args: []string{"--audit", "--format", "sarif"},
+			wantOpts: options{
+				audit:   true,
+				format:  "sarif",
+				timeout: 0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
+		{
+			name: "beneath directory",
+			args: []string{"--beneath", "/srv/app", "/srv/app/current"},
+			wantOpts: options{
+				beneath: "/srv/app",
+				timeout: 0,
+			},
+			wantPaths: []string{"/srv/app/current"},
+			wantErr:   false,
+		},
+		{
+			name: "race concurrently",
+			args: []string{"--race", "100", "--concurrent", "/srv/app/current"},
+			wantOpts: options{
+				race:       100,
+				concurrent: true,
+				timeout:    0,
+			},
+			wantPaths: []string{"/srv/app/current"},
+			wantErr:   false,
+		},
+		{
+			name:      "race invalid",
+			args:      []string{"--race", "1"},
+			wantOpts:  options{},
+			wantPaths: nil,
+			wantErr:   true,
+		},
+		{
+			name: "watch",
+			args: []string{"--watch", "-p", "/srv/app/current"},
+			wantOpts: options{
+				watch:   true,
+				mode:    true,
+				timeout: 0,
+			},
+			wantPaths: []string{"/srv/app/current"},
+			wantErr:   false,
+		},
+		{
+			name: "on-change implies watch",
+			args: []string{"--on-change", "systemctl reload app", "/srv/app/current"},
+			wantOpts: options{
+				watch:    true,
+				onChange: "systemctl reload app",
+				timeout:  0,
+			},
+			wantPaths: []string{"/srv/app/current"},
+			wantErr:   false,
+		},
+		{
+			name: "scan jobs and hops",
+			args: []string{"--jobs", "4", "--max-hops", "3", "/srv"},
+			wantOpts: options{
+				jobs:    4,
+				maxHops: 3,
+				timeout: 0,
+			},
+			wantPaths: []string{"/srv"},
+			wantErr:   false,
+		},
+		{
+			name: "refs scope",
+			args: []string{"--in", "/usr,/opt", "--exclude", "/proc", "--one-file-system", "/opt/toolchain-12"},
+			wantOpts: options{
+				in:         "/usr,/opt",
+				exclude:    "/proc",
+				oneFileSys: true,
+				timeout:    0,
+			},
+			wantPaths: []string{"/opt/toolchain-12"},
+			wantErr:   false,
+		},
+		{
+			name:      "jobs invalid",
+			args:      []string{"--jobs", "-1"},
+			wantOpts:  options{},
+			wantPaths: nil,
+			wantErr:   true,
+		},
+		{
+			name:      "watch structured format",
+			args:      []string{"--watch", "--format", "json"},
+			wantOpts:  options{},
+			wantPaths: nil,
+			wantErr:   true,
+		},
+		{
+			name:      "tree",
+			args:      []string{"--tree", "/usr/bin/python3", "/usr/bin/pip3"},
+			wantOpts:  options{tree: true, timeout: 0},
+			wantPaths: []string{"/usr/bin/python3", "/usr/bin/pip3"},
+			wantErr:   false,
+		},
+		{
+			name:      "tree structured format",
+			args:      []string{"--tree", "--format", "sarif"},
+			wantOpts:  options{},
+			wantPaths: nil,
+			wantErr:   true,
+		},
+		{
+			name:      "canonicalize zero",
+			args:      []string{"-f", "-z", "/usr/bin/python3"},
+			wantOpts:  options{canonicalize: true, zero: true, timeout: 0},
+			wantPaths: []string{"/usr/bin/python3"},
+			wantErr:   false,
+		},
+		{
+			name:      "canonicalize modes combined",
+			args:      []string{"-e", "--canonicalize-missing"},
+			wantOpts:  options{},
+			wantPaths: nil,
+			wantErr:   true,
+		},
+		{
+			name:      "zero without canonicalize",
+			args:      []string{"--zero"},
+			wantOpts:  options{},
+			wantPaths: nil,
+			wantErr:   true,
+		},
+		{
+			name:      "absolute",
+			args:      []string{"--absolute", "/bin/vi"},
+			wantOpts:  options{absolute: true, timeout: 0},
+			wantPaths: []string{"/bin/vi"},
+			wantErr:   false,
+		},
+		{
+			name:      "links only",
+			args:      []string{"--links-only", "-l", "/usr/lib/jvm/default/bin/java"},
+			wantOpts:  options{linksOnly: true, long: true, mode: true, user: true, group: true, size: true, mount: true, caps: true, timeout: 0},
+			wantPaths: []string{"/usr/lib/jvm/default/bin/java"},
+			wantErr:   false,
+		},
+		{
+			name:      "links only tree",
+			args:      []string{"--links-only", "--tree"},
+			wantOpts:  options{},
+			wantPaths: nil,
+			wantErr:   true,
+		},
+		{
+			name:      "max depth follow last",
+			args:      []string{"--max-depth", "2", "--follow-last", "/usr/bin/java"},
+			wantOpts:  options{maxDepth: 2, followLast: true, timeout: 0},
+			wantPaths: []string{"/usr/bin/java"},
+			wantErr:   false,
+		},
+		{
+			name:      "follow modes combined",
+			args:      []string{"--no-follow-last", "--follow-last"},
+			wantOpts:  options{},
+			wantPaths: nil,
+			wantErr:   true,
+		},
+		{
+			name:      "max depth invalid",
+			args:      []string{"--max-depth", "-1"},
+			wantOpts:  options{},
+			wantPaths: nil,
+			wantErr:   true,
+		},
+		{
+			name: "allocation flags",
+			args: []string{"-N", "-b", "-B"},
+			wantOpts: options{
+				nlink:   true,
+				blocks:  true,
+				blksize: true,
+				timeout: 0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
+		{
+			name: "human long flag",
+			args: []string{"--human", "-s"},
+			wantOpts: options{
+				human:   true,
+				size:    true,
+				timeout: 0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
+		{
+			name: "si flag",
+			args: []string{"--si"},
+			wantOpts: options{
+				si:      true,
+				timeout: 0,
+			},
+			wantPaths: nil,
+			wantErr:   false,
+		},
 		{
 			name: "single path",
 			args: []string{"/tmp"},
@@ -269,6 +633,7 @@ func TestParseFlags(t *testing.T) {
 				group:   true,
 				size:    true,
 				mount:   true,
+				caps:    true,
 				timeout: 0,
 			},
 			wantPaths: []string{"/tmp"},
@@ -284,6 +649,7 @@ func TestParseFlags(t *testing.T) {
 				group:   true,
 				size:    true,
 				mount:   true,
+				caps:    true,
 				timeout: 0,
 			},
 			wantPaths: []string{"/tmp"},
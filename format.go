@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+)
+
+// pathReport is the structured output produced for each path argument.
+type pathReport struct {
+	Path    string  `json:"path"`
+	Entries []entry `json:"entries"`
+}
+
+// printJSON walks each path and writes all results as a single JSON array. A
+// walk that failed is encoded up to the entry in error, and the first such
+// error is returned once the array is written.
+func printJSON(ctx context.Context, out io.Writer, paths []string, opts options, resolve resolveFunc) error {
+	var walkErr error
+	reports := make([]pathReport, 0, len(paths))
+	for _, p := range paths {
+		entries, err := resolve(ctx, p, opts)
+		if err != nil {
+			if len(entries) == 0 {
+				return err
+			}
+			if walkErr == nil {
+				walkErr = err
+			}
+		}
+		reports = append(reports, pathReport{Path: filepath.Clean(p), Entries: entries})
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(reports); err != nil {
+		return err
+	}
+	return walkErr
+}
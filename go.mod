@@ -1,5 +1,10 @@
 module github.com/ardnew/lsi
 
-go 1.25
+go 1.25.0
 
-require github.com/integrii/flaggy v1.8.0 // indirect
+require (
+	github.com/integrii/flaggy v1.8.0
+	golang.org/x/crypto v0.54.0
+	golang.org/x/sys v0.47.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -2,42 +2,96 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"unicode"
 )
 
-const indentWidth = 2
+const (
+	indentWidth = 2
+
+	// blockUnit is the size in bytes of the blocks counted by stat(2).
+	blockUnit = 512
+
+	// maxSymlinks is the number of symlinks followed while resolving a single
+	// path before giving up, as the kernel does (MAXSYMLINKS).
+	maxSymlinks = 40
+)
+
+// errSymlinkLoop indicates a symlink chain too long to resolve.
+var errSymlinkLoop = errors.New("too many levels of symbolic links")
+
+var (
+	iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	siUnits  = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+)
 
 // walkFunc is called for each path element discovered during traversal.
 type walkFunc func(ctx context.Context, e entry) (follow bool, err error)
 
 // walk traverses the given path, invoking fn for each element encountered.
 func walk(ctx context.Context, path string, fn walkFunc) error {
-	return walkRecursive(ctx, "", path, 0, fn)
+	var links int
+	return walkRecursive(ctx, "", path, 0, &links, fn)
 }
 
 // entry represents a single path element with its associated metadata.
 type entry struct {
-	Path   string
-	Volume string
-	Name   string
-	Link   string
-	Mode   string
-	Dev    uint64
-	Pdev   uint64
-	Inode  uint64
-	Size   int64
-	Uid    int
-	User   string
-	Gid    int
-	Group  string
-	Level  int
-	Info   os.FileInfo
-	Err    error
+	Path      string      `json:"path"`
+	Dest      string      `json:"dest"`
+	Volume    string      `json:"volume,omitempty"`
+	Name      string      `json:"name"`
+	Link      string      `json:"link,omitempty"`
+	Target    string      `json:"target,omitempty"`
+	Canonical string      `json:"canonical,omitempty"`
+	Mode      string      `json:"mode"`
+	Octal     string      `json:"octal"`
+	Attrs     string      `json:"attrs,omitempty"`
+	Dev       uint64      `json:"dev"`
+	Pdev      uint64      `json:"pdev"`
+	Inode     uint64      `json:"inode"`
+	Size      int64       `json:"size"`
+	Nlink     uint64      `json:"nlink"`
+	Blocks    int64       `json:"blocks"`
+	Blksize   int64       `json:"blksize"`
+	Major     uint32      `json:"major,omitempty"`
+	Minor     uint32      `json:"minor,omitempty"`
+	Uid       int         `json:"uid"`
+	User      string      `json:"user"`
+	Gid       int         `json:"gid"`
+	Group     string      `json:"group"`
+	Caps      string      `json:"caps,omitempty"`
+	DevName   string      `json:"devname,omitempty"`
+	Driver    string      `json:"driver,omitempty"`
+	Type      string      `json:"type,omitempty"`
+	Digest    string      `json:"digest,omitempty"`
+	Access    *access     `json:"access,omitempty"`
+	Contained *bool       `json:"contained,omitempty"`
+	Findings  []finding   `json:"findings,omitempty"`
+	Level     int         `json:"level"`
+	Info      os.FileInfo `json:"-"`
+	Err       error       `json:"-"`
+}
+
+// MarshalJSON encodes the entry for structured output, reporting its error
+// (if any) as a message.
+func (e entry) MarshalJSON() ([]byte, error) {
+	type plain entry
+	var msg string
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	return json.Marshal(struct {
+		plain
+		Error string `json:"error,omitempty"`
+	}{plain(e), msg})
 }
 
 // makeEntry creates an entry for the given path component.
@@ -54,10 +108,13 @@ func makeEntry(ctx context.Context, from, path, volume, name string, level int)
 	}
 
 	var (
-		link, mod, usr, grp string
+		link, mod, oct      string
+		usr, grp            string
+		major, minor        uint32
 		uid, gid            int
 		dev, pdev, inode    uint64
-		size                int64
+		size, blocks, blksz int64
+		nlink               uint64
 	)
 
 	// Build paths relative to where we are from.
@@ -70,41 +127,120 @@ func makeEntry(ctx context.Context, from, path, volume, name string, level int)
 			link, err = os.Readlink(dest)
 		}
 		mod = mode(info)
+		oct = octal(info)
 	}
 
 	if nil == err {
 		pdev = getParentDevice(dest)
 		dev, inode, size = getDeviceInfo(info)
+		nlink, blocks, blksz = getAllocInfo(info)
 		usr, grp, uid, gid, err = getOwnerInfo(info)
 	}
 
+	// Device nodes report their device number in place of a size.
+	if nil == err && 0 != info.Mode()&fs.ModeDevice {
+		major, minor = getDeviceNumber(info)
+	}
+
 	return entry{
-		Path:   path,
-		Volume: volume,
-		Name:   name,
-		Link:   link,
-		Mode:   mod,
-		Dev:    dev,
-		Pdev:   pdev,
-		Inode:  inode,
-		Size:   size,
-		Uid:    uid,
-		User:   usr,
-		Gid:    gid,
-		Group:  grp,
-		Level:  level,
-		Info:   info,
-		Err:    err,
+		Path:    path,
+		Dest:    dest,
+		Volume:  volume,
+		Name:    name,
+		Link:    link,
+		Mode:    mod,
+		Octal:   oct,
+		Dev:     dev,
+		Pdev:    pdev,
+		Inode:   inode,
+		Size:    size,
+		Nlink:   nlink,
+		Blocks:  blocks,
+		Blksize: blksz,
+		Major:   major,
+		Minor:   minor,
+		Uid:     uid,
+		User:    usr,
+		Gid:     gid,
+		Group:   grp,
+		Level:   level,
+		Info:    info,
+		Err:     err,
 	}
 }
 
 // fmtName returns the entry name with indentation and link target if applicable.
 func (e *entry) fmtName() string {
-	var link string
-	if e.Link != "" {
-		link = " -> " + e.Link
+	return fmt.Sprintf("%*s%s%s", indentWidth*e.Level, "", e.Name, e.fmtLink())
+}
+
+// fmtLink returns the link target as read, followed by its absolute form if
+// known and different, or "" if the entry is not a symlink.
+func (e *entry) fmtLink() string {
+	if e.Link == "" {
+		return ""
+	}
+	if e.Target != "" && e.Target != e.Link {
+		return " -> " + e.Link + " (" + e.Target + ")"
+	}
+	return " -> " + e.Link
+}
+
+// isDevice reports whether the entry is a block or character device node.
+func (e *entry) isDevice() bool {
+	return e.Info != nil && 0 != e.Info.Mode()&fs.ModeDevice
+}
+
+// fmtSize returns the entry size, or "major, minor" for device nodes.
+func (e *entry) fmtSize(opts options) string {
+	if e.isDevice() {
+		return fmt.Sprintf("%d, %d", e.Major, e.Minor)
+	}
+	return fmtBytes(e.Size, opts)
+}
+
+// fmtBlocks returns the number of bytes allocated to the entry.
+func (e *entry) fmtBlocks(opts options) string {
+	return fmtBytes(e.Blocks*blockUnit, opts)
+}
+
+// isSparse reports whether the entry is a regular file with fewer bytes
+// allocated than its apparent size.
+func (e *entry) isSparse() bool {
+	return e.Info != nil && e.Info.Mode().IsRegular() && e.Blocks*blockUnit < e.Size
+}
+
+// fmtBytes formats a byte count as a plain integer, or using IEC (--human) or
+// SI (--si) units. Like `ls -h`, scaled values are rounded up and shown with
+// one decimal place when less than 10.
+func fmtBytes(n int64, opts options) string {
+	var (
+		base float64
+		unit []string
+	)
+	switch {
+	case opts.si:
+		base, unit = 1000, siUnits
+	case opts.human:
+		base, unit = 1024, iecUnits
+	default:
+		return strconv.FormatInt(n, 10)
+	}
+
+	v, i := float64(n), 0
+	for v >= base && i < len(unit)-1 {
+		v /= base
+		i++
+	}
+
+	switch {
+	case i == 0:
+		return strconv.FormatInt(n, 10) + unit[i]
+	case v < 10:
+		return strconv.FormatFloat(math.Ceil(v*10)/10, 'f', 1, 64) + unit[i]
+	default:
+		return strconv.FormatFloat(math.Ceil(v), 'f', 0, 64) + unit[i]
 	}
-	return fmt.Sprintf("%*s%s%s", indentWidth*e.Level, "", e.Name, link)
 }
 
 // mode returns a symbolic string representation for filesystem attributes.
@@ -138,54 +274,95 @@ func mode(info os.FileInfo) string {
 		s[9] = upperIf('t', 0 == m&(1<<2))
 	}
 
-	if 0 != m&(fs.ModeAppend|fs.ModeExclusive|fs.ModeTemporary) {
-		s = append(s, '+')
-	}
-
 	return string(s)
 }
 
-// splitPath separates a path into its volume and element components.
-func splitPath(path string) (elem []string, volume string) {
-	// Always reduce the path lexically.
-	path = filepath.Clean(path)
-
-	// Handle absolute and relative paths differently.
-	if filepath.IsAbs(path) {
-		var root string
-		var start int
-
-		if vol := filepath.VolumeName(path); vol != "" {
-			volume = vol
-			root = vol + string(filepath.Separator)
-			start = len(vol) + 1
-		} else {
-			root = string(filepath.Separator)
-			start = 1
-		}
+// octal returns the permission bits in the numeric form accepted by chmod,
+// including the setuid (4), setgid (2), and sticky (1) bits, e.g. "4755".
+func octal(info os.FileInfo) string {
+	m := info.Mode()
+	n := uint32(m.Perm())
 
-		elem = []string{root}
-		if len(path) > start {
-			elem = append(elem, strings.Split(path[start:], string(filepath.Separator))...)
-		}
-	} else {
-		var start int
+	if 0 != m&fs.ModeSetuid {
+		n |= 04000
+	}
+	if 0 != m&fs.ModeSetgid {
+		n |= 02000
+	}
+	if 0 != m&fs.ModeSticky {
+		n |= 01000
+	}
 
-		if vol := filepath.VolumeName(path); vol != "" {
-			volume = vol
-			start = len(vol)
-		}
+	return fmt.Sprintf("%04o", n)
+}
+
+// fmtMode returns the entry permissions in the requested display style.
+func (e *entry) fmtMode(opts options) string {
+	switch opts.modeStyle {
+	case modeStyleOctal:
+		return e.Octal
+	case modeStyleBoth:
+		return e.Mode + " " + e.Octal
+	default:
+		return e.Mode
+	}
+}
+
+// splitPath separates a path into its volume and element components. Empty
+// and "." elements are dropped, but ".." is kept: which directory it names
+// depends on where the elements before it actually lead, so it cannot be
+// reduced lexically.
+func splitPath(path string) (elem []string, volume string) {
+	volume = filepath.VolumeName(path)
+	rest := path[len(volume):]
+
+	// An absolute path starts from its root, and a relative path on another
+	// volume keeps the volume on its first element.
+	prefix := volume
+	if rest != "" && os.IsPathSeparator(rest[0]) {
+		elem = []string{volume + string(filepath.Separator)}
+		prefix = ""
+	}
 
-		if len(path) > start {
-			elem = strings.Split(path, string(filepath.Separator))
+	for _, name := range strings.FieldsFunc(rest, func(r rune) bool {
+		return r < 0x80 && os.IsPathSeparator(uint8(r))
+	}) {
+		if name == "." {
+			continue
 		}
+		elem = append(elem, prefix+name)
+		prefix = ""
 	}
 
+	if len(elem) == 0 {
+		elem = []string{prefix + "."}
+	}
 	return
 }
 
-// walkRecursive recursively traverses path elements.
-func walkRecursive(ctx context.Context, from, path string, level int, fn walkFunc) error {
+// joinElem joins the elements of a path split by splitPath without reducing
+// them.
+func joinElem(elem []string) string {
+	if len(elem) == 0 {
+		return ""
+	}
+	head, rest := elem[0], strings.Join(elem[1:], string(filepath.Separator))
+	switch {
+	case rest == "":
+		return head
+	case os.IsPathSeparator(head[len(head)-1]):
+		return head + rest
+	default:
+		return head + string(filepath.Separator) + rest
+	}
+}
+
+// walkRecursive recursively traverses path elements, looking each up in the
+// directory from, or the working directory if from is empty. The count of
+// symlinks followed is shared by every level; once it exceeds maxSymlinks,
+// the target of the next symlink is reported as an entry in error and the
+// walk ends.
+func walkRecursive(ctx context.Context, from, path string, level int, links *int, fn walkFunc) error {
 	// Check for context cancellation.
 	if ctx.Err() != nil {
 		return ctx.Err()
@@ -193,9 +370,27 @@ func walkRecursive(ctx context.Context, from, path string, level int, fn walkFun
 
 	elem, volume := splitPath(path)
 
+	// dir is the directory the next element is looked up in. Once it has
+	// passed through a symlink, it names the link rather than the directory
+	// the link leads to, so it must be resolved before ".." can be applied
+	// to it or a relative link target resolved from it.
+	dir, linked := from, false
+	physical := func() string {
+		if linked {
+			dir, linked = physicalPath(dir), false
+		}
+		return dir
+	}
+
 	// Invoke callback for each path element.
 	for i, name := range elem {
-		e := makeEntry(ctx, from, filepath.Join(elem[:i+1]...), volume, name, level)
+		var e entry
+		if name == ".." {
+			e = makeParentEntry(ctx, physical(), volume, level)
+		} else {
+			e = makeEntry(ctx, dir, name, volume, name, level)
+		}
+		e.Path = joinElem(elem[:i+1])
 
 		follow, err := fn(ctx, e)
 		if nil != err {
@@ -206,17 +401,51 @@ func walkRecursive(ctx context.Context, from, path string, level int, fn walkFun
 		if follow && e.Link != "" {
 			var rel string
 			if !filepath.IsAbs(e.Link) {
-				rel = filepath.Join(from, filepath.Join(elem[:i]...))
+				rel = physical()
+			}
+			if *links++; *links > maxSymlinks {
+				loop := entry{
+					Path:  e.Link,
+					Dest:  filepath.Join(rel, e.Link),
+					Name:  e.Link,
+					Level: level + 1,
+					Err:   &os.PathError{Op: "follow", Path: e.Dest, Err: errSymlinkLoop},
+				}
+				if _, err := fn(ctx, loop); nil != err {
+					return err
+				}
+				return loop.Err
 			}
-			if err := walkRecursive(ctx, rel, e.Link, level+1, fn); nil != err {
+			if err := walkRecursive(ctx, rel, e.Link, level+1, links, fn); nil != err {
 				return err
 			}
 		}
+
+		dir = e.Dest
+		linked = linked || e.Link != ""
 	}
 
 	return nil
 }
 
+// makeParentEntry creates the entry for a ".." element looked up in the
+// physical directory dir. The system rejects ".." in anything but a
+// directory, which the lexical parent would not reveal.
+func makeParentEntry(ctx context.Context, dir, volume string, level int) entry {
+	if dir != "" {
+		if _, err := os.Lstat(dir + string(filepath.Separator) + ".."); err != nil {
+			return entry{
+				Dest:   filepath.Join(dir, ".."),
+				Volume: volume,
+				Name:   "..",
+				Level:  level,
+				Err:    err,
+			}
+		}
+	}
+	return makeEntry(ctx, dir, "..", volume, "..", level)
+}
+
 // upperIf returns the rune as uppercase if upper is true, otherwise lowercase.
 func upperIf(c rune, upper bool) rune {
 	if upper {
@@ -0,0 +1,218 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// capabilityAttr is the extended attribute holding file capabilities.
+	capabilityAttr = "security.capability"
+
+	// aclAccessAttr is the extended attribute holding the POSIX access ACL.
+	aclAccessAttr = "system.posix_acl_access"
+
+	// protectedSymlinksPath is the sysctl restricting symlink following.
+	protectedSymlinksPath = "/proc/sys/fs/protected_symlinks"
+)
+
+// sysDevPath is the sysfs directory indexing devices by major:minor number.
+const sysDevPath = "/sys/dev"
+
+// statxAttributes maps statx(2) attribute bits to their inode flag equivalent.
+var statxAttributes = []struct {
+	statx uint64
+	flag  uint32
+}{
+	{unix.STATX_ATTR_COMPRESSED, attrCompress},
+	{unix.STATX_ATTR_IMMUTABLE, attrImmutable},
+	{unix.STATX_ATTR_APPEND, attrAppend},
+	{unix.STATX_ATTR_NODUMP, attrNoDump},
+	{unix.STATX_ATTR_ENCRYPTED, attrEncrypt},
+	{unix.STATX_ATTR_VERITY, attrVerity},
+	{unix.STATX_ATTR_DAX, attrDax},
+}
+
+// getCapabilities returns the getcap-style file capabilities of the given
+// path, or an empty string if it has none (Linux-specific).
+func getCapabilities(dest string) (string, error) {
+	buf := make([]byte, vfsCapSize3)
+	n, err := syscall.Getxattr(dest, capabilityAttr, buf)
+	if err != nil {
+		if err == syscall.ENODATA || err == syscall.ENOTSUP {
+			return "", nil
+		}
+		return "", err
+	}
+	c, err := decodeCapabilities(buf[:n])
+	if err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
+
+// getAttributes returns the lsattr-style inode flags of the given path
+// (Linux-specific). Flags reported by statx are merged with those from the
+// FS_IOC_GETFLAGS ioctl, which is only attempted on regular files and
+// directories since it requires opening the file.
+func getAttributes(dest string, info os.FileInfo) (string, error) {
+	var (
+		flags uint32
+		known bool
+		stx   unix.Statx_t
+	)
+
+	err := unix.Statx(unix.AT_FDCWD, dest,
+		unix.AT_SYMLINK_NOFOLLOW|unix.AT_STATX_DONT_SYNC, 0, &stx)
+	if err == nil {
+		known = true
+		for _, a := range statxAttributes {
+			if 0 != stx.Attributes_mask&stx.Attributes&a.statx {
+				flags |= a.flag
+			}
+		}
+	}
+
+	if info.Mode().IsRegular() || info.IsDir() {
+		fd, e := unix.Open(dest,
+			unix.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+		if e == nil {
+			if f, e := unix.IoctlGetUint32(fd, unix.FS_IOC_GETFLAGS); e == nil {
+				known = true
+				flags |= f
+			}
+			unix.Close(fd)
+		}
+	}
+
+	if !known {
+		return "", err
+	}
+	return fmtAttributes(flags), nil
+}
+
+// getDeviceDriver returns the canonical kernel name and driver of the block
+// or character device with the given number, as reported by sysfs
+// (Linux-specific).
+func getDeviceDriver(block bool, major, minor uint32) (name, driver string) {
+	class := "char"
+	if block {
+		class = "block"
+	}
+	dir := filepath.Join(sysDevPath, class, fmt.Sprintf("%d:%d", major, minor))
+
+	if f, err := os.Open(filepath.Join(dir, "uevent")); err == nil {
+		scan := bufio.NewScanner(f)
+		for scan.Scan() {
+			key, val, _ := strings.Cut(scan.Text(), "=")
+			switch key {
+			case "DEVNAME":
+				name = val
+			case "DRIVER":
+				driver = val
+			}
+		}
+		f.Close()
+	}
+
+	// Most device classes only name their driver on the parent device.
+	if driver == "" {
+		if link, err := os.Readlink(filepath.Join(dir, "device", "driver")); err == nil {
+			driver = filepath.Base(link)
+		}
+	}
+	return
+}
+
+// getACL returns the raw POSIX access ACL of the given path, or nil if it has
+// none (Linux-specific). A symlink is not followed, since its target's ACL is
+// that of another entry.
+func getACL(dest string) ([]byte, error) {
+	sz, err := unix.Lgetxattr(dest, aclAccessAttr, nil)
+	if err != nil {
+		if err == unix.ENODATA || err == unix.ENOTSUP {
+			return nil, nil
+		}
+		return nil, err
+	}
+	buf := make([]byte, sz)
+	n, err := unix.Lgetxattr(dest, aclAccessAttr, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// protectedSymlinks reports whether the fs.protected_symlinks sysctl is
+// enabled (Linux-specific).
+func protectedSymlinks() bool {
+	b, err := os.ReadFile(protectedSymlinksPath)
+	return err == nil && strings.TrimSpace(string(b)) != "0"
+}
+
+// isNoexec reports whether the filesystem containing the given path is
+// mounted noexec (Linux-specific).
+func isNoexec(dest string) bool {
+	var st unix.Statfs_t
+	return unix.Statfs(dest, &st) == nil && 0 != uint64(st.Flags)&unix.ST_NOEXEC
+}
+
+// watchMask selects the inotify events that can change how a path resolves:
+// entries created, deleted or renamed in a directory, and changes to the
+// ownership or permissions of an entry. Symlinks are watched themselves.
+const watchMask = unix.IN_ATTRIB | unix.IN_CREATE | unix.IN_DELETE | unix.IN_DELETE_SELF |
+	unix.IN_MOVE_SELF | unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DONT_FOLLOW
+
+// waitChange blocks until one of the named entries changes, its filesystem
+// is unmounted, or a filesystem is mounted or unmounted anywhere, or until
+// ctx is done (Linux-specific). Entries that cannot be watched are skipped.
+// Once watching, it returns at once if changed reports true, so that nothing
+// is missed between the entries being found and watched.
+func waitChange(ctx context.Context, names []string, changed func() bool) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return os.NewSyscallError("inotify_init1", err)
+	}
+	defer unix.Close(fd)
+
+	for _, name := range names {
+		_, _ = unix.InotifyAddWatch(fd, name, watchMask)
+	}
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+
+	// The mount table signals a change with POLLPRI, which catches a
+	// filesystem mounted over a directory of the chain. Polling consumes the
+	// signal, so the file is opened directly rather than with os.Open, whose
+	// descriptors the runtime polls too.
+	if mfd, err := unix.Open("/proc/self/mountinfo", unix.O_RDONLY|unix.O_CLOEXEC, 0); err == nil {
+		defer unix.Close(mfd)
+		fds = append(fds, unix.PollFd{Fd: int32(mfd), Events: unix.POLLPRI})
+	}
+	if changed() {
+		return nil
+	}
+
+	// Poll with a timeout to notice when ctx is done.
+	for ctx.Err() == nil {
+		n, err := unix.Poll(fds, 100)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return os.NewSyscallError("poll", err)
+		}
+		if n > 0 {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
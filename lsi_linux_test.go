@@ -0,0 +1,50 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// TestGetACLSymlink tests that the ACL of a symlink's target is not
+// reported for the link itself.
+func TestGetACLSymlink(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink("file", link); err != nil {
+		t.Fatal(err)
+	}
+
+	// Grant nobody read and write access beyond the mode bits.
+	acl := binary.LittleEndian.AppendUint32(nil, aclVersion)
+	for _, e := range []aclEntry{
+		{Tag: aclUserObj, Perm: 6, ID: 0xffffffff},
+		{Tag: aclUser, Perm: 6, ID: 65534},
+		{Tag: aclGroupObj, Perm: 4, ID: 0xffffffff},
+		{Tag: aclMask, Perm: 6, ID: 0xffffffff},
+		{Tag: aclOther, Perm: 4, ID: 0xffffffff},
+	} {
+		acl = binary.LittleEndian.AppendUint16(acl, e.Tag)
+		acl = binary.LittleEndian.AppendUint16(acl, e.Perm)
+		acl = binary.LittleEndian.AppendUint32(acl, e.ID)
+	}
+	if err := unix.Setxattr(file, aclAccessAttr, acl, 0); err != nil {
+		t.Skipf("setting an ACL: %v", err)
+	}
+
+	if got, err := getACL(file); err != nil || len(got) != len(acl) {
+		t.Errorf("getACL(file) = %v, %v, want the ACL set", got, err)
+	}
+	if got, err := getACL(link); err != nil || got != nil {
+		t.Errorf("getACL(link) = %v, %v, want none", got, err)
+	}
+}
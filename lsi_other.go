@@ -0,0 +1,48 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// getCapabilities returns the file capabilities of the given path (stub).
+func getCapabilities(dest string) (string, error) {
+	// File capabilities are a Linux-only feature
+	return "", nil
+}
+
+// getAttributes returns the inode attribute flags of the given path (stub).
+func getAttributes(dest string, info os.FileInfo) (string, error) {
+	// Inode attribute flags are a Linux-only feature
+	return "", nil
+}
+
+// getDeviceDriver returns the kernel name and driver of a device (stub).
+func getDeviceDriver(block bool, major, minor uint32) (name, driver string) {
+	// Device driver lookup relies on Linux sysfs
+	return
+}
+
+// getACL returns the raw POSIX access ACL of the given path (stub).
+func getACL(dest string) ([]byte, error) {
+	// POSIX ACLs are read from Linux extended attributes
+	return nil, nil
+}
+
+// protectedSymlinks reports whether symlink following is restricted (stub).
+func protectedSymlinks() bool {
+	return false
+}
+
+// isNoexec reports whether the filesystem of the given path is noexec (stub).
+func isNoexec(dest string) bool {
+	return false
+}
+
+// waitChange blocks until one of the named entries changes (stub).
+func waitChange(ctx context.Context, names []string, changed func() bool) error {
+	return errors.New("watching is not supported on this platform")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -65,7 +66,7 @@ func TestSplitPath(t *testing.T) {
 		{
 			name:       "complex relative",
 			path:       "./foo/../bar",
-			wantElem:   []string{"bar"},
+			wantElem:   []string{"foo", "..", "bar"},
 			wantVolume: "",
 		},
 		{
@@ -258,14 +259,13 @@ func TestModeSetuid(t *testing.T) {
 		}
 	}
 
-	// Test with ModeAppend, ModeExclusive, or ModeTemporary
+	// Append-only and immutable files are shown by --attrs, not by a marker.
 	info := mockFileInfo{
 		mode: 0644 | fs.ModeAppend,
 		name: "test",
 	}
-	got := mode(info)
-	if !strings.HasSuffix(got, "+") {
-		t.Errorf("mode() with ModeAppend = %q, want '+' suffix", got)
+	if got := mode(info); got != "-rw-r--r--" {
+		t.Errorf("mode() with ModeAppend = %q, want %q", got, "-rw-r--r--")
 	}
 
 	// Test setuid without owner read bit (to trigger uppercase S)
@@ -349,6 +349,16 @@ func TestEntryFmtName(t *testing.T) {
 			},
 			wantContain: "deep -> /absolute/path",
 		},
+		{
+			name: "with absolute target",
+			entry: entry{
+				Name:   "vi",
+				Link:   "../../etc/alternatives/vi",
+				Target: "/etc/alternatives/vi",
+				Level:  1,
+			},
+			wantContain: "vi -> ../../etc/alternatives/vi (/etc/alternatives/vi)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -369,6 +379,105 @@ func TestEntryFmtName(t *testing.T) {
 	}
 }
 
+// TestEntryFmtSize tests size formatting for files and device nodes.
+func TestEntryFmtSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry entry
+		want  string
+	}{
+		{
+			name:  "regular file",
+			entry: entry{Size: 2048, Info: mockFileInfo{mode: 0644}},
+			want:  "2048",
+		},
+		{
+			name:  "no file info",
+			entry: entry{Size: 7},
+			want:  "7",
+		},
+		{
+			name:  "block device",
+			entry: entry{Major: 8, Minor: 1, Info: mockFileInfo{mode: fs.ModeDevice | 0660}},
+			want:  "8, 1",
+		},
+		{
+			name:  "character device",
+			entry: entry{Major: 1, Minor: 3, Info: mockFileInfo{mode: fs.ModeDevice | fs.ModeCharDevice | 0666}},
+			want:  "1, 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.fmtSize(options{}); got != tt.want {
+				t.Errorf("fmtSize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFmtBytes tests plain and human-readable byte counts.
+func TestFmtBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		opts options
+		want string
+	}{
+		{0, options{}, "0"},
+		{123456, options{}, "123456"},
+		{512, options{human: true}, "512B"},
+		{1024, options{human: true}, "1.0KiB"},
+		{1536, options{human: true}, "1.5KiB"},
+		{1025, options{human: true}, "1.1KiB"},
+		{10 * 1024, options{human: true}, "10KiB"},
+		{5 << 20, options{human: true}, "5.0MiB"},
+		{999, options{si: true}, "999B"},
+		{1000, options{si: true}, "1.0kB"},
+		{2500000, options{si: true}, "2.5MB"},
+		{1000, options{human: true, si: true}, "1.0kB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := fmtBytes(tt.n, tt.opts); got != tt.want {
+				t.Errorf("fmtBytes(%d, %+v) = %q, want %q", tt.n, tt.opts, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEntryIsSparse tests sparse file detection.
+func TestEntryIsSparse(t *testing.T) {
+	tmpDir := t.TempDir()
+	sparse := filepath.Join(tmpDir, "sparse")
+	f, err := os.Create(sparse)
+	if err != nil {
+		t.Fatalf("Failed to create sparse file: %v", err)
+	}
+	if err := f.Truncate(64 << 20); err != nil {
+		f.Close()
+		t.Fatalf("Failed to extend sparse file: %v", err)
+	}
+	f.Close()
+
+	e := makeEntry(context.Background(), tmpDir, "sparse", "", "sparse", 0)
+	if e.Err != nil {
+		t.Fatalf("makeEntry() error = %v", e.Err)
+	}
+	if !e.isSparse() {
+		t.Errorf("isSparse() = false for %d bytes in %d blocks, want true", e.Size, e.Blocks)
+	}
+	if e.Nlink != 1 {
+		t.Errorf("makeEntry() Nlink = %d, want 1", e.Nlink)
+	}
+
+	dir := makeEntry(context.Background(), "", tmpDir, "", tmpDir, 0)
+	if dir.isSparse() {
+		t.Error("isSparse() = true for directory, want false")
+	}
+}
+
 // TestMakeEntry tests entry creation.
 func TestMakeEntry(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -708,6 +817,97 @@ func TestWalkCircularSymlink(t *testing.T) {
 	}
 }
 
+// TestWalkSymlinkLoop tests that a walk following every symlink of a loop
+// ends with an entry in error instead of recursing forever.
+func TestWalkSymlinkLoop(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.Symlink("b", filepath.Join(tmpDir, "a")); err != nil {
+		t.Fatalf("Failed to create a: %v", err)
+	}
+	if err := os.Symlink("a", filepath.Join(tmpDir, "b")); err != nil {
+		t.Fatalf("Failed to create b: %v", err)
+	}
+
+	entries, err := collectEntries(context.Background(), filepath.Join(tmpDir, "a"), options{})
+	if !errors.Is(err, errSymlinkLoop) {
+		t.Fatalf("collectEntries() error = %v, want %v", err, errSymlinkLoop)
+	}
+	last := entries[len(entries)-1]
+	if !errors.Is(last.Err, errSymlinkLoop) || last.Level != maxSymlinks+1 {
+		t.Errorf("collectEntries() last entry = %+v, want loop error at level %d", last, maxSymlinks+1)
+	}
+}
+
+// TestWalkRelativeLinkThroughLink tests that the relative target of a link
+// reached through another link is resolved from the directory physically
+// holding it.
+func TestWalkRelativeLinkThroughLink(t *testing.T) {
+	tmpDir := physicalPath(t.TempDir())
+	if err := os.MkdirAll(filepath.Join(tmpDir, "a", "b"), 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	if err := os.Symlink("../../a", filepath.Join(tmpDir, "a", "b", "up")); err != nil {
+		t.Fatalf("Failed to create up: %v", err)
+	}
+
+	// The second "up" is reached through the first, so its ".." components
+	// leave the physical a/b, not the link.
+	entries, err := collectEntries(context.Background(), filepath.Join(tmpDir, "a", "b", "up", "b", "up"), options{})
+	if err != nil {
+		t.Fatalf("collectEntries() error = %v, want nil", err)
+	}
+	if last := entries[len(entries)-1]; last.Dest != filepath.Join(tmpDir, "a") {
+		t.Errorf("collectEntries() last entry = %s, want %s", last.Dest, filepath.Join(tmpDir, "a"))
+	}
+}
+
+// TestWalkParentThroughLink tests that ".." leaves the directory a symlink
+// before it leads to, as the system resolves it, rather than the link.
+func TestWalkParentThroughLink(t *testing.T) {
+	base := physicalPath(t.TempDir())
+	if err := os.MkdirAll(filepath.Join(base, "d", "e"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"x", filepath.Join("d", "x")} {
+		if err := os.WriteFile(filepath.Join(base, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range map[string]string{"le": "d/e", "lx": "le/../x"} {
+		if err := os.Symlink(target, filepath.Join(base, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const sep = string(filepath.Separator)
+	tests := []struct {
+		name     string
+		path     string
+		wantDest string
+		wantErr  error
+	}{
+		{"parent of link", "le/..", "d", nil},
+		{"grandparent of link", "le/../..", "", nil},
+		{"parent in link target", "lx", "d/x", nil},
+		{"sibling of link target", "le/../d", "d/d", fs.ErrNotExist},
+		{"parent of file", "x/..", "", syscall.ENOTDIR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The path is not cleaned, since that would reduce its "..".
+			entries, err := collectEntries(context.Background(), base+sep+filepath.FromSlash(tt.path), options{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("collectEntries() error = %v, want %v", err, tt.wantErr)
+			}
+			want := filepath.Join(base, filepath.FromSlash(tt.wantDest))
+			if last := entries[len(entries)-1]; last.Dest != want {
+				t.Errorf("collectEntries() last entry = %s, want %s", last.Dest, want)
+			}
+		})
+	}
+}
+
 // TestWalkAbsolutePath tests walking absolute paths.
 func TestWalkAbsolutePath(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -798,6 +998,53 @@ func TestModeSpecialBits(t *testing.T) {
 	}
 }
 
+// TestOctal tests numeric permission formatting including special bits.
+func TestOctal(t *testing.T) {
+	tests := []struct {
+		mode os.FileMode
+		want string
+	}{
+		{0644, "0644"},
+		{fs.ModeDir | 0755, "0755"},
+		{fs.ModeSetuid | 0755, "4755"},
+		{fs.ModeSetgid | fs.ModeDir | 0775, "2775"},
+		{fs.ModeSticky | fs.ModeDir | 0777, "1777"},
+		{fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky | 0700, "7700"},
+		{fs.ModeSymlink | 0777, "0777"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := octal(mockFileInfo{mode: tt.mode}); got != tt.want {
+				t.Errorf("octal(%v) = %q, want %q", tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEntryFmtMode tests each permission display style.
+func TestEntryFmtMode(t *testing.T) {
+	e := entry{Mode: "-rwsr-xr-x", Octal: "4755"}
+
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{"", "-rwsr-xr-x"},
+		{modeStyleSymbolic, "-rwsr-xr-x"},
+		{modeStyleOctal, "4755"},
+		{modeStyleBoth, "-rwsr-xr-x 4755"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			if got := e.fmtMode(options{modeStyle: tt.style}); got != tt.want {
+				t.Errorf("fmtMode(%q) = %q, want %q", tt.style, got, tt.want)
+			}
+		})
+	}
+}
+
 // mockFileInfo implements os.FileInfo for testing.
 type mockFileInfo struct {
 	name  string
@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
 // getDeviceInfo extracts device, inode, and size from file info (Unix-specific).
@@ -20,6 +22,27 @@ func getDeviceInfo(info os.FileInfo) (dev, inode uint64, size int64) {
 	return
 }
 
+// getAllocInfo extracts the link count, allocated 512-byte blocks, and
+// preferred I/O block size from file info (Unix-specific).
+func getAllocInfo(info os.FileInfo) (nlink uint64, blocks, blksize int64) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		nlink = uint64(stat.Nlink)
+		blocks = int64(stat.Blocks)
+		blksize = int64(stat.Blksize)
+	}
+	return
+}
+
+// getDeviceNumber extracts the major and minor numbers of a device node
+// (Unix-specific).
+func getDeviceNumber(info os.FileInfo) (major, minor uint32) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		major = unix.Major(uint64(stat.Rdev))
+		minor = unix.Minor(uint64(stat.Rdev))
+	}
+	return
+}
+
 // getParentDevice gets the device ID of the parent directory (Unix-specific).
 func getParentDevice(dest string) uint64 {
 	pdev := ^uint64(0) // Default: invalid device ID
@@ -13,6 +13,18 @@ func getDeviceInfo(info os.FileInfo) (dev, inode uint64, size int64) {
 	return
 }
 
+// getAllocInfo extracts the link count and block allocation from file info
+// (Windows stub).
+func getAllocInfo(info os.FileInfo) (nlink uint64, blocks, blksize int64) {
+	return
+}
+
+// getDeviceNumber extracts the major and minor numbers of a device node
+// (Windows stub).
+func getDeviceNumber(info os.FileInfo) (major, minor uint32) {
+	return
+}
+
 // getParentDevice gets the device ID of the parent directory (Windows stub).
 func getParentDevice(dest string) uint64 {
 	return ^uint64(0) // Invalid device ID
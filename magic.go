@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// sniffLength is the number of leading bytes examined to identify content.
+const sniffLength = 512
+
+// magicSignatures lists simple content signatures found at a fixed offset.
+var magicSignatures = []struct {
+	offset int
+	magic  string
+	desc   string
+}{
+	{0, "\x1f\x8b", "gzip compressed data"},
+	{0, "BZh", "bzip2 compressed data"},
+	{0, "\xfd7zXZ\x00", "XZ compressed data"},
+	{0, "\x28\xb5\x2f\xfd", "Zstandard compressed data"},
+	{0, "PK\x03\x04", "Zip archive data"},
+	{0, "!<arch>\n", "ar archive"},
+	{0, "\xed\xab\xee\xdb", "RPM package"},
+	{0, "\x89PNG\r\n\x1a\n", "PNG image data"},
+	{0, "\xff\xd8\xff", "JPEG image data"},
+	{0, "GIF87a", "GIF image data"},
+	{0, "GIF89a", "GIF image data"},
+	{0, "%PDF-", "PDF document"},
+	{0, "\x00asm", "WebAssembly binary module"},
+	{0, "SQLite format 3\x00", "SQLite 3.x database"},
+	{257, "ustar", "POSIX tar archive"},
+}
+
+// elfMachines names the most common ELF architectures the way file(1) does.
+var elfMachines = map[elf.Machine]string{
+	elf.EM_386:     "Intel 80386",
+	elf.EM_X86_64:  "x86-64",
+	elf.EM_ARM:     "ARM",
+	elf.EM_AARCH64: "ARM aarch64",
+	elf.EM_RISCV:   "RISC-V",
+	elf.EM_PPC:     "PowerPC",
+	elf.EM_PPC64:   "64-bit PowerPC",
+	elf.EM_MIPS:    "MIPS",
+	elf.EM_S390:    "IBM S/390",
+}
+
+// machoCPUs names the Mach-O architectures the way lipo(1) does.
+var machoCPUs = map[macho.Cpu]string{
+	macho.Cpu386:   "i386",
+	macho.CpuAmd64: "x86_64",
+	macho.CpuArm:   "arm",
+	macho.CpuArm64: "arm64",
+	macho.CpuPpc:   "ppc",
+	macho.CpuPpc64: "ppc64",
+}
+
+// peMachines names the most common PE architectures.
+var peMachines = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_I386:  "Intel 80386",
+	pe.IMAGE_FILE_MACHINE_AMD64: "x86-64",
+	pe.IMAGE_FILE_MACHINE_ARMNT: "ARM",
+	pe.IMAGE_FILE_MACHINE_ARM64: "Aarch64",
+}
+
+// sniffType describes the content of the file at dest, similar to file(1).
+// Only regular files are opened; other file types are described by mode.
+func sniffType(dest string, info os.FileInfo) (string, error) {
+	if t := describeMode(info.Mode()); t != "" {
+		return t, nil
+	}
+
+	f, err := os.Open(dest)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	return describeContent(f, head[:n]), nil
+}
+
+// describeMode names the file type of m, or returns "" for a regular file.
+func describeMode(m fs.FileMode) string {
+	switch {
+	case m.IsDir():
+		return "directory"
+	case 0 != m&fs.ModeSymlink:
+		return "symbolic link"
+	case 0 != m&fs.ModeNamedPipe:
+		return "fifo (named pipe)"
+	case 0 != m&fs.ModeSocket:
+		return "socket"
+	case 0 != m&fs.ModeCharDevice:
+		return "character special"
+	case 0 != m&fs.ModeDevice:
+		return "block special"
+	case 0 != m&fs.ModeIrregular:
+		return "irregular file"
+	}
+	return ""
+}
+
+// describeContent identifies content from its leading bytes. Executable
+// formats are parsed further from r to report their class and architecture.
+func describeContent(r io.ReaderAt, head []byte) string {
+	switch {
+	case len(head) == 0:
+		return "empty"
+	case bytes.HasPrefix(head, []byte(elf.ELFMAG)):
+		return describeELF(r)
+	case isMachO(head):
+		return describeMachO(r, head)
+	case bytes.HasPrefix(head, []byte("MZ")):
+		return describePE(r)
+	}
+
+	if interp, args, ok := parseShebang(head); ok {
+		return "script (#!" + strings.Join(append([]string{interp}, args...), " ") + ")"
+	}
+
+	for _, sig := range magicSignatures {
+		if len(head) >= sig.offset+len(sig.magic) &&
+			string(head[sig.offset:sig.offset+len(sig.magic)]) == sig.magic {
+			return sig.desc
+		}
+	}
+
+	return describeText(head)
+}
+
+// parseShebang extracts the interpreter and its arguments from a "#!" line.
+func parseShebang(head []byte) (interp string, args []string, ok bool) {
+	if !bytes.HasPrefix(head, []byte("#!")) {
+		return "", nil, false
+	}
+	line, _, _ := bytes.Cut(head[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	return fields[0], fields[1:], true
+}
+
+// describeText distinguishes ASCII and UTF-8 text from binary data.
+func describeText(head []byte) string {
+	// The sniffed prefix may end in the middle of a multi-byte rune.
+	text := head
+	for i := 0; i < utf8.UTFMax && len(text) > 0 && !utf8.Valid(text); i++ {
+		text = text[:len(text)-1]
+	}
+	if bytes.IndexByte(head, 0) >= 0 || !utf8.Valid(text) {
+		return "data"
+	}
+	for _, b := range head {
+		if b >= utf8.RuneSelf {
+			return "UTF-8 text"
+		}
+	}
+	return "ASCII text"
+}
+
+// describeELF reports the class, byte order, type, architecture, and linkage
+// of an ELF object.
+func describeELF(r io.ReaderAt) string {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return "ELF (corrupt)"
+	}
+	defer f.Close()
+
+	bits := "32-bit"
+	if f.Class == elf.ELFCLASS64 {
+		bits = "64-bit"
+	}
+	order := "LSB"
+	if f.Data == elf.ELFDATA2MSB {
+		order = "MSB"
+	}
+
+	var interp string
+	var dynamic bool
+	for _, p := range f.Progs {
+		switch p.Type {
+		case elf.PT_INTERP:
+			b, _ := io.ReadAll(p.Open())
+			interp = string(bytes.TrimRight(b, "\x00"))
+		case elf.PT_DYNAMIC:
+			dynamic = true
+		}
+	}
+
+	var kind string
+	switch f.Type {
+	case elf.ET_EXEC:
+		kind = "executable"
+	case elf.ET_DYN:
+		kind = "shared object"
+		if isPIE(f, interp) {
+			kind = "pie executable"
+		}
+	case elf.ET_REL:
+		kind = "relocatable"
+	case elf.ET_CORE:
+		kind = "core file"
+	default:
+		kind = f.Type.String()
+	}
+
+	arch, ok := elfMachines[f.Machine]
+	if !ok {
+		arch = f.Machine.String()
+	}
+
+	desc := []string{fmt.Sprintf("ELF %s %s %s", bits, order, kind), arch}
+	switch {
+	case f.Type != elf.ET_EXEC && f.Type != elf.ET_DYN:
+	case dynamic:
+		desc = append(desc, "dynamically linked")
+	default:
+		desc = append(desc, "statically linked")
+	}
+	if interp != "" {
+		desc = append(desc, "interpreter "+interp)
+	}
+
+	return strings.Join(desc, ", ")
+}
+
+// isPIE reports whether a shared ELF object is a position-independent
+// executable. Linkers mark these with DF_1_PIE; older objects without the
+// flag are assumed executable if they request a program interpreter.
+func isPIE(f *elf.File, interp string) bool {
+	if flags, err := f.DynValue(elf.DT_FLAGS_1); err == nil && len(flags) > 0 {
+		return 0 != flags[0]&uint64(elf.DF_1_PIE)
+	}
+	return interp != ""
+}
+
+// isMachO reports whether head begins with a Mach-O (or universal) magic.
+func isMachO(head []byte) bool {
+	if len(head) < 8 {
+		return false
+	}
+	switch binary.BigEndian.Uint32(head) {
+	case macho.Magic32, macho.Magic64:
+		return true
+	case macho.MagicFat:
+		// Java class files share the universal binary magic, but store their
+		// version where a universal binary stores its (small) arch count.
+		return binary.BigEndian.Uint32(head[4:]) < 20
+	}
+	switch binary.LittleEndian.Uint32(head) {
+	case macho.Magic32, macho.Magic64:
+		return true
+	}
+	return false
+}
+
+// describeMachO reports the class, type, and architectures of a Mach-O file.
+func describeMachO(r io.ReaderAt, head []byte) string {
+	if binary.BigEndian.Uint32(head) == macho.MagicFat {
+		ff, err := macho.NewFatFile(r)
+		if err != nil {
+			return "Mach-O universal binary (corrupt)"
+		}
+		defer ff.Close()
+
+		var arch []string
+		for _, a := range ff.Arches {
+			arch = append(arch, machoCPU(a.Cpu))
+		}
+		return fmt.Sprintf("Mach-O universal binary with %d architectures: [%s]",
+			len(arch), strings.Join(arch, ", "))
+	}
+
+	f, err := macho.NewFile(r)
+	if err != nil {
+		return "Mach-O (corrupt)"
+	}
+	defer f.Close()
+
+	bits := "32-bit"
+	if f.Magic == macho.Magic64 {
+		bits = "64-bit"
+	}
+
+	var kind string
+	switch f.Type {
+	case macho.TypeExec:
+		kind = "executable"
+	case macho.TypeDylib:
+		kind = "dynamically linked shared library"
+	case macho.TypeBundle:
+		kind = "bundle"
+	case macho.TypeObj:
+		kind = "object"
+	default:
+		kind = f.Type.String()
+	}
+
+	return fmt.Sprintf("Mach-O %s %s, %s", bits, kind, machoCPU(f.Cpu))
+}
+
+// machoCPU returns the name of a Mach-O CPU type.
+func machoCPU(cpu macho.Cpu) string {
+	if name, ok := machoCPUs[cpu]; ok {
+		return name
+	}
+	return cpu.String()
+}
+
+// describePE reports the format, type, and architecture of a PE executable,
+// falling back to plain MS-DOS executables.
+func describePE(r io.ReaderAt) string {
+	f, err := pe.NewFile(r)
+	if err != nil {
+		return "MS-DOS executable"
+	}
+	defer f.Close()
+
+	format := "PE32"
+	if _, ok := f.OptionalHeader.(*pe.OptionalHeader64); ok {
+		format = "PE32+"
+	}
+
+	kind := "executable"
+	if 0 != f.Characteristics&pe.IMAGE_FILE_DLL {
+		kind = "executable (DLL)"
+	}
+
+	arch, ok := peMachines[f.Machine]
+	if !ok {
+		arch = fmt.Sprintf("machine %#04x", f.Machine)
+	}
+
+	return fmt.Sprintf("%s %s, %s", format, kind, arch)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestDescribeContent tests identification of content by leading bytes.
+func TestDescribeContent(t *testing.T) {
+	tar := make([]byte, sniffLength)
+	copy(tar[257:], "ustar\x0000")
+
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"empty", nil, "empty"},
+		{"ascii text", []byte("hello, world\n"), "ASCII text"},
+		{"utf-8 text", []byte("héllo\n"), "UTF-8 text"},
+		{"truncated utf-8 text", []byte("héllo é")[:8], "UTF-8 text"},
+		{"binary data", []byte{0x01, 0x00, 0x02, 0x03}, "data"},
+		{"shell script", []byte("#!/bin/sh -e\necho hi\n"), "script (#!/bin/sh -e)"},
+		{"env script", []byte("#! /usr/bin/env python3\n"), "script (#!/usr/bin/env python3)"},
+		{"gzip", []byte("\x1f\x8b\x08\x00"), "gzip compressed data"},
+		{"xz", []byte("\xfd7zXZ\x00\x00"), "XZ compressed data"},
+		{"zip", []byte("PK\x03\x04\x14\x00"), "Zip archive data"},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00"), "PNG image data"},
+		{"pdf", []byte("%PDF-1.7\n"), "PDF document"},
+		{"tar", tar, "POSIX tar archive"},
+		{"java class", []byte("\xca\xfe\xba\xbe\x00\x00\x00\x34"), "data"},
+		{"ms-dos", []byte("MZ\x90\x00"), "MS-DOS executable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeContent(bytes.NewReader(tt.head), tt.head); got != tt.want {
+				t.Errorf("describeContent(%q) = %q, want %q", tt.head, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseShebang tests interpreter extraction from "#!" lines.
+func TestParseShebang(t *testing.T) {
+	tests := []struct {
+		head       string
+		wantInterp string
+		wantArgs   int
+		wantOK     bool
+	}{
+		{"#!/bin/sh\n", "/bin/sh", 0, true},
+		{"#!/usr/bin/env -S python3 -u\n", "/usr/bin/env", 3, true},
+		{"#!  /bin/bash  \r\n", "/bin/bash", 0, true},
+		{"#!\n", "", 0, false},
+		{"echo hi\n", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.head, func(t *testing.T) {
+			interp, args, ok := parseShebang([]byte(tt.head))
+			if interp != tt.wantInterp || len(args) != tt.wantArgs || ok != tt.wantOK {
+				t.Errorf("parseShebang(%q) = %q, %q, %v; want %q, %d args, %v",
+					tt.head, interp, args, ok, tt.wantInterp, tt.wantArgs, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestSniffType tests content detection of files on disk.
+func TestSniffType(t *testing.T) {
+	tmpDir := t.TempDir()
+	script := filepath.Join(tmpDir, "script")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Failed to locate test executable: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantPrefix string
+	}{
+		{"directory", tmpDir, "directory"},
+		{"script", script, "script (#!/bin/sh)"},
+	}
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd":
+		tests = append(tests, struct {
+			name       string
+			path       string
+			wantPrefix string
+		}{"executable", exe, "ELF "})
+	case "darwin":
+		tests = append(tests, struct {
+			name       string
+			path       string
+			wantPrefix string
+		}{"executable", exe, "Mach-O "})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := os.Lstat(tt.path)
+			if err != nil {
+				t.Fatalf("Lstat(%q) error = %v", tt.path, err)
+			}
+			got, err := sniffType(tt.path, info)
+			if err != nil {
+				t.Fatalf("sniffType(%q) error = %v", tt.path, err)
+			}
+			if !strings.HasPrefix(got, tt.wantPrefix) {
+				t.Errorf("sniffType(%q) = %q, want prefix %q", tt.path, got, tt.wantPrefix)
+			}
+		})
+	}
+}
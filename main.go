@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -15,11 +17,13 @@ const (
 	command = "lsi"
 
 	mountPointSymbol = "@"
+	deniedSymbol     = "!"
 )
 
 // widths tracks the maximum width needed for each column.
 type widths struct {
-	mode, user, group, size, inode int
+	mode, attrs, nlink, user, group, size, blocks, blksize, inode int
+	canonical                                                     int
 }
 
 // contextError creates an error describing why context was canceled.
@@ -31,14 +35,20 @@ func contextError(ctx context.Context, start time.Time) error {
 	return ctx.Err()
 }
 
-// printError formats and prints an entry error.
+// printError formats and prints an entry error, followed by any findings
+// attached to the entry.
 func printError(w io.Writer, e entry) {
+	var text string
 	switch err := e.Err.(type) {
 	case *os.PathError:
-		fmt.Fprintf(w, " * %s (%s): %s\n", e.Name, err.Path, err.Err)
+		text = fmt.Sprintf(" * %s (%s): %s", e.Name, err.Path, err.Err)
 	default:
-		fmt.Fprintf(w, " * %s: %s\n", e.Name, err)
+		text = fmt.Sprintf(" * %s: %s", e.Name, err)
 	}
+	for _, f := range e.Findings {
+		text += " [" + f.String() + "]"
+	}
+	fmt.Fprintln(w, text)
 }
 
 func main() {
@@ -71,6 +81,15 @@ func run(ctx context.Context, out, errOut io.Writer, args []string) error {
 		}
 	}
 
+	// Subcommands that check the resolved paths accept the same flags.
+	var subcommand string
+	if len(args) > 0 {
+		switch args[0] {
+		case execCheckCommand, checkCommand, snapshotCommand, verifyCommand, scanCommand, refsCommand, diffCommand:
+			subcommand, args = args[0], args[1:]
+		}
+	}
+
 	// Parse command-line flags.
 	opts, paths, err := parseFlags(args)
 	if err != nil {
@@ -84,6 +103,58 @@ func run(ctx context.Context, out, errOut io.Writer, args []string) error {
 	}
 
 	// Determine the file paths to analyze.
+	if len(paths) == 0 && subcommand != "" {
+		operand := "PATH"
+		switch subcommand {
+		case verifyCommand:
+			operand = "BASELINE"
+		case scanCommand:
+			operand = "DIR"
+		case refsCommand:
+			operand = "TARGET"
+		}
+		return fmt.Errorf("%s: missing %s", subcommand, operand)
+	}
+
+	resolve := resolvePath
+	rules := builtinRules
+	var pol *policy
+	switch subcommand {
+	case execCheckCommand:
+		resolve = resolveExec
+	case checkCommand:
+		if opts.policy == "" {
+			return fmt.Errorf("%s: missing --policy", subcommand)
+		}
+		if pol, err = loadPolicy(opts.policy); err != nil {
+			return err
+		}
+		resolve, rules = pol.resolve, maps.Clone(builtinRules)
+		maps.Copy(rules, pol.rules())
+	case diffCommand:
+		if len(paths) < 2 {
+			return fmt.Errorf("%s: missing PATH", subcommand)
+		}
+		if len(paths) > 2 {
+			return fmt.Errorf("%s: unexpected argument %q", subcommand, paths[2])
+		}
+	case refsCommand:
+		if len(paths) > 1 {
+			return fmt.Errorf("%s: unexpected argument %q", subcommand, paths[1])
+		}
+	case verifyCommand:
+		if len(paths) > 1 {
+			return fmt.Errorf("%s: unexpected argument %q", subcommand, paths[1])
+		}
+		base, err := loadBaseline(paths[0])
+		if err != nil {
+			return err
+		}
+		// Walk the recorded paths the same way they were snapshotted.
+		resolve, paths = base.resolve, base.paths()
+		opts.noFollow, opts.maxDepth = base.NoFollow, base.MaxDepth
+		opts.noFollowLast, opts.followLast = base.NoFollowLast, base.FollowLast
+	}
 	if len(paths) == 0 {
 		// If no paths were given, use PWD.
 		wd, err := os.Getwd()
@@ -100,6 +171,97 @@ func run(ctx context.Context, out, errOut io.Writer, args []string) error {
 		defer cancel()
 	}
 
+	if opts.watch {
+		if subcommand != "" {
+			return fmt.Errorf("%s: --watch is not supported", subcommand)
+		}
+		return watchPaths(ctx, out, errOut, paths, opts)
+	}
+
+	if opts.canonicalize || opts.canonicalizeExisting || opts.canonicalizeMissing {
+		if subcommand != "" {
+			return fmt.Errorf("%s: canonicalize modes are not supported", subcommand)
+		}
+		return printCanonical(ctx, out, paths, opts)
+	}
+
+	switch subcommand {
+	case snapshotCommand:
+		return printSnapshot(ctx, out, paths, opts)
+	case diffCommand:
+		return printDiff(ctx, out, paths[0], paths[1], opts)
+	}
+
+	// Scan the trees first, then print the symlinks with findings.
+	var scanned *scanResult
+	switch subcommand {
+	case scanCommand:
+		scanned, err = scanTrees(ctx, errOut, paths, opts)
+	case refsCommand:
+		scanned, err = findRefs(ctx, errOut, paths[0], opts)
+	}
+	if err != nil {
+		return err
+	}
+	if scanned != nil {
+		resolve, paths = scanned.resolve, scanned.paths
+	}
+
+	// Count the findings of every path checked by a subcommand or audit.
+	var found int
+	check := func(ctx context.Context, path string, opts options) ([]entry, error) {
+		entries, err := resolve(ctx, path, opts)
+		found += countFindings(entries)
+		return entries, err
+	}
+
+	if err := printPaths(ctx, out, paths, opts, check, rules); err != nil {
+		return err
+	}
+
+	if scanned != nil && (opts.format == "" || opts.format == formatText) {
+		if len(paths) > 0 {
+			fmt.Fprintln(out)
+		}
+		scanned.printSummary(out)
+	}
+
+	if pol != nil {
+		if opts.format == "" || opts.format == formatText {
+			fmt.Fprintln(out)
+			pol.printSummary(out)
+		}
+		if n := pol.failed(); n > 0 {
+			return fmt.Errorf("%d of %d rules failed", n, len(pol.Rules))
+		}
+		if pol.unresolved > 0 {
+			return fmt.Errorf("%d path(s) could not be resolved", pol.unresolved)
+		}
+	}
+
+	if found > 0 {
+		return fmt.Errorf("%d finding(s)", found)
+	}
+	return nil
+}
+
+// resolveFunc walks a single path and returns its annotated entries.
+type resolveFunc func(ctx context.Context, path string, opts options) ([]entry, error)
+
+// printPaths resolves and prints each path in the requested format. Findings
+// are described from the given rules.
+func printPaths(ctx context.Context, out io.Writer, paths []string, opts options, resolve resolveFunc, rules map[string]ruleInfo) error {
+	// Structured formats encode every path as a single document.
+	switch opts.format {
+	case formatJSON:
+		return printJSON(ctx, out, paths, opts, resolve)
+	case formatSARIF:
+		return printSARIF(ctx, out, paths, opts, resolve, rules)
+	}
+	if opts.tree {
+		return printTree(ctx, out, paths, opts, resolve)
+	}
+
 	// Process each path.
 	for i, p := range paths {
 		// If more than one path provided, print a header for the current path.
@@ -108,7 +270,7 @@ func run(ctx context.Context, out, errOut io.Writer, args []string) error {
 			fmt.Fprintf(out, "-- %s\n", fp)
 		}
 
-		if err := processPath(ctx, out, p, opts); err != nil {
+		if err := processPath(ctx, out, p, opts, resolve); err != nil {
 			return err
 		}
 
@@ -120,21 +282,78 @@ func run(ctx context.Context, out, errOut io.Writer, args []string) error {
 	return nil
 }
 
-// processPath walks a single path and prints its entries.
-func processPath(ctx context.Context, out io.Writer, path string, opts options) error {
+// processPath walks a single path and prints its entries, returning the
+// error of a walk that failed once its entries are printed.
+func processPath(ctx context.Context, out io.Writer, path string, opts options, resolve resolveFunc) error {
+	entries, err := resolve(ctx, path, opts)
+	if opts.linksOnly {
+		entries = linksOnly(entries)
+	}
+
+	// A walk that failed is printed up to the entry in error.
+	w := calculateWidths(entries, opts)
+	printEntries(out, entries, opts, w)
+	return err
+}
+
+// linksOnly returns the entries that are symlinks, mount points or in error,
+// or have findings, along with the final endpoint, leaving out the plain
+// directories in between.
+func linksOnly(entries []entry) []entry {
+	var kept []entry
+	for i, e := range entries {
+		if e.Link != "" || e.Dev != e.Pdev || e.Err != nil || len(e.Findings) > 0 || i == len(entries)-1 {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// resolvePath walks a single path and annotates the entries collected. If the
+// walk fails, the entries collected up to and including the one in error are
+// annotated and returned along with its error.
+func resolvePath(ctx context.Context, path string, opts options) ([]entry, error) {
 	start := time.Now()
 
-	entries, err := collectEntries(ctx, path, opts)
-	if err != nil {
-		if ctx.Err() != nil {
-			return contextError(ctx, start)
+	var (
+		entries []entry
+		walkErr error
+		err     error
+	)
+	if opts.race > 0 {
+		entries, walkErr = raceEntries(ctx, path, opts)
+	} else {
+		entries, walkErr = collectEntries(ctx, path, opts)
+	}
+	if opts.absolute {
+		resolveTargets(ctx, entries)
+	}
+	if walkErr == nil {
+		err = annotateEndpoint(ctx, entries, opts)
+	}
+	if err == nil && opts.as != "" {
+		var id identity
+		if id, err = lookupIdentity(opts.as); err == nil {
+			evalAccess(entries, id)
 		}
-		return err
+	}
+	if err == nil && opts.beneath != "" {
+		err = evalBeneath(entries, opts.beneath)
+	}
+	if err == nil && opts.audit {
+		var trusted map[int]bool
+		if trusted, err = lookupTrusted(opts.trust); err == nil {
+			auditEntries(entries, trusted)
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, contextError(ctx, start)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	w := calculateWidths(entries)
-	printEntries(out, entries, opts, w)
-	return nil
+	return entries, walkErr
 }
 
 // collectEntries performs the path walk and collects all entries.
@@ -151,21 +370,115 @@ func collectEntries(ctx context.Context, path string, opts options) ([]entry, er
 		if ctx.Err() != nil {
 			return false, ctx.Err()
 		}
-		return !opts.noFollow, nil
+		return opts.follows(path, e), nil
 	})
+	annotateEntries(entries, opts)
 
 	return entries, err
 }
 
+// follows reports whether the walk of path should follow the symlink of e.
+// The last component of path is the one at level 0 whose path is all of
+// path, and the links followed beyond it are those resolving it.
+func (o options) follows(path string, e entry) bool {
+	elem, _ := splitPath(path)
+	last := e.Level == 0 && e.Path == joinElem(elem)
+	switch {
+	case o.noFollow:
+		return false
+	case o.maxDepth > 0 && e.Level >= o.maxDepth:
+		return false
+	case o.noFollowLast:
+		return !last
+	case o.followLast:
+		return last || e.Level > 0
+	}
+	return true
+}
+
+// annotateEntries adds the details requested by --attrs, --caps and --device
+// to each entry walked. Since each opens or reads beyond the entry itself,
+// they are only looked up when asked for.
+func annotateEntries(entries []entry, opts options) {
+	if !opts.attrs && !opts.caps && !opts.device {
+		return
+	}
+	for i := range entries {
+		e := &entries[i]
+		if e.Err != nil || e.Info == nil {
+			continue
+		}
+
+		// Not every filesystem supports inode attribute flags; failing to
+		// read them should not prevent the entry from being reported.
+		if opts.attrs {
+			e.Attrs, _ = getAttributes(e.Dest, e.Info)
+		}
+
+		// Likewise for capabilities, which only apply to regular files.
+		if opts.caps && e.Info.Mode().IsRegular() {
+			e.Caps, _ = getCapabilities(e.Dest)
+		}
+
+		if opts.device && e.isDevice() {
+			e.DevName, e.Driver = getDeviceDriver(0 == e.Info.Mode()&fs.ModeCharDevice, e.Major, e.Minor)
+		}
+	}
+}
+
+// annotateEndpoint adds the requested details of the resolved endpoint, which
+// is always the last entry collected.
+func annotateEndpoint(ctx context.Context, entries []entry, opts options) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	e := &entries[len(entries)-1]
+	if e.Err != nil || e.Info == nil {
+		return nil
+	}
+
+	if opts.fileType {
+		t, err := sniffType(e.Dest, e.Info)
+		if err != nil {
+			t = "unknown: " + unwrapPathError(err).Error()
+		}
+		e.Type = t
+	}
+
+	if opts.digest != "" && e.Info.Mode().IsRegular() {
+		d, err := digestFile(ctx, e.Dest, opts.digest)
+		if err != nil {
+			return err
+		}
+		e.Digest = d
+	}
+
+	return nil
+}
+
+// unwrapPathError returns the underlying cause of a *os.PathError, whose
+// path is redundant when reported alongside an entry.
+func unwrapPathError(err error) error {
+	if pe, ok := err.(*os.PathError); ok {
+		return pe.Err
+	}
+	return err
+}
+
 // calculateWidths determines the maximum width for each column.
-func calculateWidths(entries []entry) widths {
+func calculateWidths(entries []entry, opts options) widths {
 	var w widths
 	for _, e := range entries {
-		w.mode = max(w.mode, len(e.Mode))
+		w.mode = max(w.mode, len(e.fmtMode(opts)))
+		w.attrs = max(w.attrs, len(e.Attrs))
+		w.nlink = max(w.nlink, len(strconv.FormatUint(e.Nlink, 10)))
 		w.user = max(w.user, len(e.User))
 		w.group = max(w.group, len(e.Group))
-		w.size = max(w.size, len(strconv.FormatInt(e.Size, 10)))
+		w.size = max(w.size, len(e.fmtSize(opts)))
+		w.blocks = max(w.blocks, len(e.fmtBlocks(opts)))
+		w.blksize = max(w.blksize, len(fmtBytes(e.Blksize, opts)))
 		w.inode = max(w.inode, len(strconv.FormatUint(e.Inode, 10)))
+		w.canonical = max(w.canonical, len(e.Canonical))
 	}
 	return w
 }
@@ -183,11 +496,27 @@ func printEntries(w io.Writer, entries []entry, opts options, widths widths) {
 
 // print outputs an entry with the specified formatting options.
 func (e *entry) print(w io.Writer, opts options, widths widths) {
+	name := e.Name
+	if !opts.noFollow {
+		name = e.fmtName()
+	}
+	e.printAs(w, name, opts, widths)
+}
+
+// printAs outputs an entry as print does, with name in place of its indented
+// name.
+func (e *entry) printAs(w io.Writer, name string, opts options, widths widths) {
 	var column []string
 
 	// Add a uniform-width column for each requested property.
 	if opts.mode {
-		column = append(column, fmt.Sprintf("%*s", widths.mode, e.Mode))
+		column = append(column, fmt.Sprintf("%*s", widths.mode, e.fmtMode(opts)))
+	}
+	if opts.attrs {
+		column = append(column, fmt.Sprintf("%*s", widths.attrs, e.Attrs))
+	}
+	if opts.nlink {
+		column = append(column, fmt.Sprintf("%*d", widths.nlink, e.Nlink))
 	}
 	if opts.user {
 		column = append(column, fmt.Sprintf("%*s", widths.user, e.User))
@@ -196,11 +525,30 @@ func (e *entry) print(w io.Writer, opts options, widths widths) {
 		column = append(column, fmt.Sprintf("%*s", widths.group, e.Group))
 	}
 	if opts.size {
-		column = append(column, fmt.Sprintf("%*d", widths.size, e.Size))
+		column = append(column, fmt.Sprintf("%*s", widths.size, e.fmtSize(opts)))
+	}
+	if opts.blocks {
+		column = append(column, fmt.Sprintf("%*s", widths.blocks, e.fmtBlocks(opts)))
+	}
+	if opts.blksize {
+		column = append(column, fmt.Sprintf("%*s", widths.blksize, fmtBytes(e.Blksize, opts)))
 	}
 	if opts.inode {
 		column = append(column, fmt.Sprintf("%*d", widths.inode, e.Inode))
 	}
+	if opts.absolute {
+		column = append(column, fmt.Sprintf("%-*s", widths.canonical, e.Canonical))
+	}
+	if opts.as != "" {
+		var mark, perm string
+		if e.Access != nil {
+			perm = e.Access.Perm
+			if e.Access.first {
+				mark = deniedSymbol
+			}
+		}
+		column = append(column, fmt.Sprintf("%*s%*s", len(deniedSymbol), mark, len("rwx"), perm))
+	}
 	if opts.mount {
 		var ind string
 		if e.Dev != e.Pdev {
@@ -209,12 +557,47 @@ func (e *entry) print(w io.Writer, opts options, widths widths) {
 		column = append(column, fmt.Sprintf("%*s", len(mountPointSymbol), ind))
 	}
 
-	// Append the indented name (with possible link target).
-	name := e.Name
-	if !opts.noFollow {
-		name = e.fmtName()
+	// Append any annotations requested for this entry.
+	for _, a := range e.annotations(opts) {
+		name += " [" + a + "]"
 	}
 
 	// Join columns together with separator.
 	fmt.Fprintln(w, strings.Join(append(column, name), " "))
 }
+
+// annotations returns the supplementary details to display after the name.
+func (e *entry) annotations(opts options) []string {
+	var note []string
+	if opts.caps && e.Caps != "" {
+		note = append(note, e.Caps)
+	}
+	if opts.fileType && e.Type != "" {
+		note = append(note, e.Type)
+	}
+	if e.Digest != "" {
+		note = append(note, e.Digest)
+	}
+	if e.Access != nil && e.Access.Reason != "" {
+		note = append(note, "denied: "+e.Access.Reason)
+	}
+	for _, f := range e.Findings {
+		note = append(note, f.String())
+	}
+	if opts.blocks && e.isSparse() {
+		note = append(note, "sparse")
+	}
+	if opts.device && e.isDevice() {
+		var dev []string
+		if e.DevName != "" {
+			dev = append(dev, "devname="+e.DevName)
+		}
+		if e.Driver != "" {
+			dev = append(dev, "driver="+e.Driver)
+		}
+		if len(dev) > 0 {
+			note = append(note, strings.Join(dev, " "))
+		}
+	}
+	return note
+}
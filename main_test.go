@@ -3,10 +3,14 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -414,7 +418,7 @@ func TestProcessPath(t *testing.T) {
 		long:     false,
 	}
 
-	err := processPath(ctx, &out, testFile, opts)
+	err := processPath(ctx, &out, testFile, opts, resolvePath)
 	if err != nil {
 		t.Errorf("processPath() error = %v, want nil", err)
 	}
@@ -557,7 +561,7 @@ func TestCalculateWidths(t *testing.T) {
 		},
 	}
 
-	w := calculateWidths(entries)
+	w := calculateWidths(entries, options{})
 
 	if w.mode < 10 {
 		t.Errorf("mode width = %d, want >= 10", w.mode)
@@ -579,7 +583,7 @@ func TestCalculateWidths(t *testing.T) {
 // TestCalculateWidthsEmpty tests width calculation with empty slice.
 func TestCalculateWidthsEmpty(t *testing.T) {
 	entries := []entry{}
-	w := calculateWidths(entries)
+	w := calculateWidths(entries, options{})
 
 	// All widths should be zero
 	if w.mode != 0 || w.user != 0 || w.group != 0 || w.size != 0 || w.inode != 0 {
@@ -610,7 +614,7 @@ func TestPrintEntries(t *testing.T) {
 		},
 	}
 
-	w := calculateWidths(entries)
+	w := calculateWidths(entries, options{})
 	opts := options{
 		mode:  true,
 		user:  true,
@@ -644,7 +648,7 @@ func TestPrintEntriesWithError(t *testing.T) {
 		},
 	}
 
-	w := calculateWidths(entries)
+	w := calculateWidths(entries, options{})
 	opts := options{}
 
 	var buf bytes.Buffer
@@ -667,6 +671,7 @@ func TestEntryPrint(t *testing.T) {
 	e := &entry{
 		Name:  "testfile",
 		Mode:  "-rw-r--r--",
+		Attrs: "----i---------e-------",
 		User:  "testuser",
 		Group: "testgroup",
 		Size:  2048,
@@ -704,6 +709,11 @@ func TestEntryPrint(t *testing.T) {
 			opts:        options{mode: true, user: true, group: true, size: true, inode: true},
 			wantContain: []string{"-rw-r--r--", "testuser", "testgroup", "2048", "5678", "testfile"},
 		},
+		{
+			name:        "attributes",
+			opts:        options{attrs: true},
+			wantContain: []string{"----i---------e-------", "testfile"},
+		},
 		{
 			name:        "mount point",
 			opts:        options{mount: true},
@@ -789,6 +799,799 @@ func TestEntryPrintFollowSymlink(t *testing.T) {
 	}
 }
 
+// TestEntryPrintCapabilities tests the file capability annotation.
+func TestEntryPrintCapabilities(t *testing.T) {
+	e := &entry{
+		Name: "caddy",
+		Caps: "cap_net_bind_service=ep",
+	}
+
+	var buf bytes.Buffer
+	e.print(&buf, options{caps: true}, widths{})
+	if output := buf.String(); !strings.Contains(output, "caddy [cap_net_bind_service=ep]") {
+		t.Errorf("print() output = %q, want capability annotation", output)
+	}
+
+	buf.Reset()
+	e.print(&buf, options{}, widths{})
+	if output := buf.String(); strings.Contains(output, "cap_") {
+		t.Errorf("print() output = %q, want no capability annotation without --caps", output)
+	}
+}
+
+// TestEntryPrintDevice tests device number and driver output.
+func TestEntryPrintDevice(t *testing.T) {
+	e := &entry{
+		Name:    "vda",
+		Major:   254,
+		Minor:   0,
+		DevName: "vda",
+		Driver:  "virtio_blk",
+		Info:    mockFileInfo{mode: fs.ModeDevice | 0600},
+	}
+
+	var buf bytes.Buffer
+	opts := options{size: true, device: true}
+	e.print(&buf, opts, calculateWidths([]entry{*e}, opts))
+	output := buf.String()
+
+	for _, want := range []string{"254, 0", "vda [devname=vda driver=virtio_blk]"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("print() output = %q, want to contain %q", output, want)
+		}
+	}
+}
+
+// TestEntryPrintAccess tests the simulated access column and denial reason.
+func TestEntryPrintAccess(t *testing.T) {
+	e := &entry{
+		Name: "priv",
+		Access: &access{
+			Perm:   "---",
+			Need:   "search",
+			Denied: true,
+			Reason: "no search permission (nobody as other: ---)",
+			first:  true,
+		},
+	}
+
+	var buf bytes.Buffer
+	e.print(&buf, options{as: "nobody"}, widths{})
+	want := "!--- priv [denied: no search permission (nobody as other: ---)]\n"
+	if output := buf.String(); output != want {
+		t.Errorf("print() output = %q, want %q", output, want)
+	}
+
+	e.Access.first = false
+	buf.Reset()
+	e.print(&buf, options{as: "nobody"}, widths{})
+	if output := buf.String(); !strings.HasPrefix(output, " --- priv") {
+		t.Errorf("print() output = %q, want no denial mark after the first", output)
+	}
+}
+
+// TestEntryPrintAllocation tests the link count, allocation, and block size
+// columns, including sparse file detection and human-readable sizes.
+func TestEntryPrintAllocation(t *testing.T) {
+	e := entry{
+		Name:    "disk.img",
+		Size:    10 << 30,
+		Nlink:   3,
+		Blocks:  8,
+		Blksize: 4096,
+		Info:    mockFileInfo{mode: 0644},
+	}
+
+	tests := []struct {
+		name        string
+		opts        options
+		wantContain []string
+	}{
+		{
+			name:        "raw bytes",
+			opts:        options{nlink: true, size: true, blocks: true, blksize: true},
+			wantContain: []string{"3 10737418240 4096 4096 disk.img [sparse]"},
+		},
+		{
+			name:        "human",
+			opts:        options{size: true, blocks: true, blksize: true, human: true},
+			wantContain: []string{"10GiB 4.0KiB 4.0KiB disk.img [sparse]"},
+		},
+		{
+			name:        "si",
+			opts:        options{size: true, si: true},
+			wantContain: []string{"11GB disk.img"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			e.print(&buf, tt.opts, calculateWidths([]entry{e}, tt.opts))
+			output := buf.String()
+
+			for _, want := range tt.wantContain {
+				if !strings.Contains(output, want) {
+					t.Errorf("print() output = %q, want to contain %q", output, want)
+				}
+			}
+		})
+	}
+}
+
+// TestRunWithType tests the content type annotation on the final target.
+func TestRunWithType(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "tool.sh")
+	if err := os.WriteFile(target, []byte("#!/bin/sh\necho hi\n"), 0755); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	link := filepath.Join(tmpDir, "tool")
+	if err := os.Symlink("tool.sh", link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	if err := run(context.Background(), &out, &errOut, []string{"--type", link}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if last := lines[len(lines)-1]; !strings.HasSuffix(last, "tool.sh [script (#!/bin/sh)]") {
+		t.Errorf("run() last line = %q, want script annotation on endpoint", last)
+	}
+	if n := strings.Count(out.String(), "["); n != 1 {
+		t.Errorf("run() output has %d annotations, want 1:\n%s", n, out.String())
+	}
+}
+
+// TestRunWithTextDangling tests that text output prints a dangling link up to
+// the entry in error before the error is returned.
+func TestRunWithTextDangling(t *testing.T) {
+	tmpDir := physicalPath(t.TempDir())
+	link := filepath.Join(tmpDir, "link")
+	if err := os.Symlink("missing", link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	err := run(context.Background(), &out, &errOut, []string{link})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("run() error = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	want := "link -> missing\n * missing (" + filepath.Join(tmpDir, "missing") + "): no such file or directory\n"
+	if !strings.HasSuffix(out.String(), want) {
+		t.Errorf("run() output = %q, want to end with %q", out.String(), want)
+	}
+}
+
+// TestRunWithJSONDangling tests that structured output encodes a dangling
+// link up to the entry in error, along with every other path.
+func TestRunWithJSONDangling(t *testing.T) {
+	tmpDir := t.TempDir()
+	link := filepath.Join(tmpDir, "link")
+	if err := os.Symlink("missing", link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	err := run(context.Background(), &out, &errOut, []string{"--format", "json", link, tmpDir})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("run() error = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	var reports []struct {
+		Entries []struct {
+			Name  string
+			Error string
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &reports); err != nil {
+		t.Fatalf("run() output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(reports) != 2 {
+		t.Fatalf("run() reported %d paths, want 2", len(reports))
+	}
+	entries := reports[0].Entries
+	if last := entries[len(entries)-1]; last.Name != "missing" || !strings.Contains(last.Error, "no such file") {
+		t.Errorf("run() last entry = %+v, want missing with its error", last)
+	}
+	for _, e := range entries[:len(entries)-1] {
+		if e.Error != "" {
+			t.Errorf("run() entry %s has error %q, want none", e.Name, e.Error)
+		}
+	}
+}
+
+// TestRunWithJSONDigest tests structured output including the digest of the
+// resolved endpoint.
+func TestRunWithJSONDigest(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "target")
+	if err := os.WriteFile(target, []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	link := filepath.Join(tmpDir, "link")
+	if err := os.Symlink("target", link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	args := []string{"--format", "json", "--digest", "sha256", link, tmpDir}
+	if err := run(context.Background(), &out, &errOut, args); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	var reports []struct {
+		Path    string
+		Entries []struct {
+			Name   string
+			Link   string
+			Digest string
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &reports); err != nil {
+		t.Fatalf("run() output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(reports) != 2 {
+		t.Fatalf("run() reported %d paths, want 2", len(reports))
+	}
+
+	entries := reports[0].Entries
+	last := entries[len(entries)-1]
+	if last.Name != "target" || last.Digest != "sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" {
+		t.Errorf("run() endpoint = %+v, want target with its sha256 digest", last)
+	}
+
+	// Directories have no content to hash.
+	dir := reports[1].Entries
+	if d := dir[len(dir)-1].Digest; d != "" {
+		t.Errorf("run() directory digest = %q, want none", d)
+	}
+}
+
+// TestRunWithExecCheck tests the exec-check subcommand and its exit status.
+func TestRunWithExecCheck(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := filepath.Join(tmpDir, "tool")
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\necho hi\n"), 0755); err != nil {
+		t.Fatalf("Failed to create tool: %v", err)
+	}
+	data := filepath.Join(tmpDir, "data")
+	if err := os.WriteFile(data, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	if err := run(context.Background(), &out, &errOut, []string{"exec-check", tool}); err != nil {
+		t.Errorf("run() error = %v, want nil for executable script", err)
+	}
+	if strings.Contains(out.String(), "[") {
+		t.Errorf("run() output = %q, want no findings", out.String())
+	}
+
+	out.Reset()
+	err := run(context.Background(), &out, &errOut, []string{"exec-check", data})
+	if err == nil || !strings.Contains(err.Error(), "1 finding") {
+		t.Errorf("run() error = %v, want 1 finding", err)
+	}
+	if !strings.Contains(out.String(), "data [exec-permission: no execute permission") {
+		t.Errorf("run() output = %q, want exec-permission finding on data", out.String())
+	}
+
+	if err := run(context.Background(), &out, &errOut, []string{"exec-check"}); err == nil {
+		t.Error("run() error = nil, want missing PATH error")
+	}
+}
+
+// TestRunWithAudit tests that audit findings are reported and fail the run.
+func TestRunWithAudit(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "authorized_keys")
+	if err := os.WriteFile(target, []byte("ssh-ed25519 AAAA\n"), 0600); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	if err := os.Chmod(target, 0666); err != nil {
+		t.Fatalf("Failed to chmod target: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	err := run(context.Background(), &out, &errOut, []string{"--audit", target})
+	if err == nil || !strings.Contains(err.Error(), "finding") {
+		t.Errorf("run() error = %v, want findings error", err)
+	}
+	if !strings.Contains(out.String(), "authorized_keys [audit-writable: writable by group") {
+		t.Errorf("run() output = %q, want audit-writable finding", out.String())
+	}
+
+	out.Reset()
+	if err := run(context.Background(), &out, &errOut, []string{"--audit", "--trust", "no-such-user-lsi", target}); err == nil {
+		t.Error("run() error = nil, want unknown trusted user error")
+	}
+}
+
+// TestRunWithCheck tests policy evaluation and its per-rule summary.
+func TestRunWithCheck(t *testing.T) {
+	tmpDir := t.TempDir()
+	key := filepath.Join(tmpDir, "server.key")
+	if err := os.WriteFile(key, []byte("key"), 0600); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := os.Chmod(key, 0644); err != nil {
+		t.Fatalf("Failed to chmod key: %v", err)
+	}
+	rules := filepath.Join(tmpDir, "rules.yaml")
+	policy := "rules:\n" +
+		"  - name: key-mode\n    paths: [\"**/*.key\"]\n    max-mode: \"0600\"\n" +
+		"  - name: contained\n    beneath: " + tmpDir + "\n" +
+		"  - name: unused\n    paths: [\"/nowhere/**\"]\n    owner: root\n"
+	if err := os.WriteFile(rules, []byte(policy), 0644); err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	err := run(context.Background(), &out, &errOut, []string{"check", "--policy", rules, key})
+	if err == nil || err.Error() != "1 of 3 rules failed" {
+		t.Errorf("run() error = %v, want 1 of 3 rules failed", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"server.key [key-mode: mode 0644 exceeds 0600]",
+		"FAIL key-mode (1 of 1 paths)",
+		"PASS contained (1 of 1 paths)",
+		"SKIP unused (no paths matched)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("run() output = %q, want to contain %q", output, want)
+		}
+	}
+
+	// A path that cannot be resolved fails the rules applying to it, and
+	// the paths after it are still checked.
+	missing := filepath.Join(tmpDir, "missing", "other.key")
+	out.Reset()
+	err = run(context.Background(), &out, &errOut, []string{"check", "--policy", rules, missing, key})
+	if err == nil || err.Error() != "2 of 3 rules failed" {
+		t.Errorf("run() error = %v, want 2 of 3 rules failed", err)
+	}
+
+	output = out.String()
+	for _, want := range []string{
+		"[key-mode: cannot resolve: no such file or directory]",
+		"[contained: cannot resolve: no such file or directory]",
+		"server.key [key-mode: mode 0644 exceeds 0600]",
+		"FAIL key-mode (2 of 2 paths)",
+		"FAIL contained (1 of 2 paths)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("run() output = %q, want to contain %q", output, want)
+		}
+	}
+
+	// With no rule applying, an unresolvable path still fails the run.
+	unused := filepath.Join(tmpDir, "unused.yaml")
+	if err := os.WriteFile(unused, []byte("rules:\n  - name: unused\n    paths: [\"/nowhere/**\"]\n    owner: root\n"), 0644); err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	out.Reset()
+	err = run(context.Background(), &out, &errOut, []string{"check", "--policy", unused, missing, key})
+	if err == nil || err.Error() != "1 path(s) could not be resolved" {
+		t.Errorf("run() error = %v, want 1 path(s) could not be resolved", err)
+	}
+	if !strings.Contains(out.String(), "SKIP unused (no paths matched)") {
+		t.Errorf("run() output = %q, want summary", out.String())
+	}
+
+	if err := run(context.Background(), &out, &errOut, []string{"check", key}); err == nil {
+		t.Error("run() error = nil, want missing --policy error")
+	}
+}
+
+// TestRunWithSARIF tests that audit findings are written as SARIF results.
+func TestRunWithSARIF(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "sudoers")
+	if err := os.WriteFile(target, []byte("root ALL=(ALL) ALL\n"), 0600); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	if err := os.Chmod(target, 0602); err != nil {
+		t.Fatalf("Failed to chmod target: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	if err := run(context.Background(), &out, &errOut, []string{"--audit", "--format", "sarif", target}); err == nil {
+		t.Error("run() error = nil, want findings error")
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("run() output is not valid SARIF: %v\n%s", err, out.String())
+	}
+	var found bool
+	for _, r := range log.Runs[0].Results {
+		if r.RuleID == ruleAuditWritable &&
+			r.Locations[0].PhysicalLocation.ArtifactLocation.URI == "file://"+filepath.ToSlash(target) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("run() results = %+v, want audit-writable at %s", log.Runs[0].Results, target)
+	}
+}
+
+// TestRunWithBeneath tests that links escaping the tree are reported.
+func TestRunWithBeneath(t *testing.T) {
+	tmpDir := t.TempDir()
+	tree := filepath.Join(tmpDir, "tree")
+	if err := os.Mkdir(tree, 0755); err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	link := filepath.Join(tree, "config")
+	if err := os.Symlink("../outside", link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(tmpDir, "outside"), 0755); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	err := run(context.Background(), &out, &errOut, []string{"--beneath", tree, link})
+	if err == nil {
+		t.Error("run() error = nil, want findings error")
+	}
+	want := fmt.Sprintf(`config -> ../outside [beneath-escape: leaves %s at ".." in ../outside, reaching`, tree)
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("run() output = %q, want to contain %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := run(context.Background(), &out, &errOut, []string{"--beneath", tmpDir, link}); err != nil {
+		t.Errorf("run() error = %v, want nil for contained link", err)
+	}
+}
+
+// TestRunWithSnapshot tests that verify reports a symlink retargeted since
+// the snapshot was taken.
+func TestRunWithSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"v1", "v2"} {
+		if err := os.Mkdir(filepath.Join(tmpDir, name), 0755); err != nil {
+			t.Fatalf("Failed to create release: %v", err)
+		}
+	}
+	link := filepath.Join(tmpDir, "current")
+	if err := os.Symlink("v1", link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	if err := run(context.Background(), &out, &errOut, []string{"snapshot", link}); err != nil {
+		t.Fatalf("run() snapshot error = %v", err)
+	}
+	baseline := filepath.Join(tmpDir, "baseline.json")
+	if err := os.WriteFile(baseline, out.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write baseline: %v", err)
+	}
+
+	out.Reset()
+	if err := run(context.Background(), &out, &errOut, []string{"verify", baseline}); err != nil {
+		t.Errorf("run() verify error = %v, want nil before changes", err)
+	}
+
+	// Links not followed when snapshotted are not followed when verified.
+	out.Reset()
+	if err := run(context.Background(), &out, &errOut, []string{"snapshot", "--no-follow-last", link}); err != nil {
+		t.Fatalf("run() snapshot error = %v", err)
+	}
+	unfollowed := filepath.Join(tmpDir, "unfollowed.json")
+	if err := os.WriteFile(unfollowed, out.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write baseline: %v", err)
+	}
+	out.Reset()
+	if err := run(context.Background(), &out, &errOut, []string{"verify", unfollowed}); err != nil {
+		t.Errorf("run() verify error = %v, want nil for links not followed", err)
+	}
+
+	if err := os.Remove(link); err != nil {
+		t.Fatalf("Failed to remove symlink: %v", err)
+	}
+	if err := os.Symlink("v2", link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	out.Reset()
+	err := run(context.Background(), &out, &errOut, []string{"verify", baseline})
+	if err == nil {
+		t.Error("run() verify error = nil, want findings error")
+	}
+	want := `current -> v2 [drift-link: target changed from "v1" to "v2"]`
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("run() output = %q, want to contain %q", out.String(), want)
+	}
+
+	// A removed target is drift, and later paths are still verified.
+	out.Reset()
+	if err := run(context.Background(), &out, &errOut, []string{"snapshot", link, filepath.Join(tmpDir, "v1")}); err != nil {
+		t.Fatalf("run() snapshot error = %v", err)
+	}
+	if err := os.WriteFile(baseline, out.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write baseline: %v", err)
+	}
+	if err := os.Remove(filepath.Join(tmpDir, "v2")); err != nil {
+		t.Fatalf("Failed to remove release: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(tmpDir, "v1"), 0700); err != nil {
+		t.Fatalf("Failed to change mode: %v", err)
+	}
+	out.Reset()
+	err = run(context.Background(), &out, &errOut, []string{"verify", baseline})
+	if err == nil || err.Error() != "2 finding(s)" {
+		t.Errorf("run() verify error = %v, want 2 finding(s)", err)
+	}
+	for _, want := range []string{
+		"no such file or directory [drift-chain: missing]",
+		"v1 [drift-mode: mode changed from ",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("run() output = %q, want to contain %q", out.String(), want)
+		}
+	}
+
+	if err := run(context.Background(), &out, &errOut, []string{"verify"}); err == nil ||
+		err.Error() != "verify: missing BASELINE" {
+		t.Errorf("run() verify error = %v, want missing BASELINE", err)
+	}
+}
+
+// TestRunWithScan tests that scan reports broken symlinks and a summary.
+func TestRunWithScan(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.Symlink("missing", filepath.Join(tmpDir, "dangling")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Symlink(".", filepath.Join(tmpDir, "self")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	err := run(context.Background(), &out, &errOut, []string{"scan", tmpDir})
+	if err == nil || err.Error() != "1 finding(s)" {
+		t.Errorf("run() error = %v, want 1 finding(s)", err)
+	}
+	for _, want := range []string{
+		"dangling -> missing [scan-dangling: ",
+		"Scanned 2 symlinks in 1 directories\n  1 scan-dangling\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("run() output = %q, want to contain %q", out.String(), want)
+		}
+	}
+
+	if err := run(context.Background(), &out, &errOut, []string{"scan"}); err == nil ||
+		err.Error() != "scan: missing DIR" {
+		t.Errorf("run() error = %v, want missing DIR", err)
+	}
+}
+
+// TestRunWithRefs tests that refs reports the symlinks resolving through the
+// target.
+func TestRunWithRefs(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "toolchain-12")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	if err := os.Symlink("toolchain-12", filepath.Join(tmpDir, "current")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	err := run(context.Background(), &out, &errOut, []string{"refs", "--in", tmpDir, target})
+	if err == nil || err.Error() != "1 finding(s)" {
+		t.Errorf("run() error = %v, want 1 finding(s)", err)
+	}
+	want := "toolchain-12 [refs-target: resolves through " + target + "]"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("run() output = %q, want to contain %q", out.String(), want)
+	}
+
+	// A target nothing refers to is safe to remove, so refs succeeds.
+	unused := filepath.Join(tmpDir, "toolchain-11")
+	if err := os.Mkdir(unused, 0755); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	if err := run(context.Background(), &out, &errOut, []string{"refs", "--in", tmpDir, unused}); err != nil {
+		t.Errorf("run() error = %v, want nil for unreferenced target", err)
+	}
+
+	if err := run(context.Background(), &out, &errOut, []string{"refs", target, tmpDir}); err == nil ||
+		!strings.Contains(err.Error(), "unexpected argument") {
+		t.Errorf("run() error = %v, want unexpected argument", err)
+	}
+}
+
+// TestRunWithDiff tests that diff reports where two paths diverge and
+// whether they reach the same target.
+func TestRunWithDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"python3.11", "python3.12"} {
+		if err := os.Mkdir(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	for name, target := range map[string]string{
+		"python":  "python3.11",
+		"python3": "python",
+		"next":    "python3.12",
+	} {
+		if err := os.Symlink(target, filepath.Join(tmpDir, name)); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+
+	var out, errOut bytes.Buffer
+	err := run(context.Background(), &out, &errOut, []string{"diff", filepath.Join(tmpDir, "python3"), filepath.Join(tmpDir, "python")})
+	if err != nil {
+		t.Errorf("run() error = %v, want nil", err)
+	}
+	for _, want := range []string{"< python3 -> python\n", "  python -> python3.11\n", "target:   same"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("run() output = %q, want to contain %q", out.String(), want)
+		}
+	}
+
+	out.Reset()
+	err = run(context.Background(), &out, &errOut, []string{"diff", filepath.Join(tmpDir, "python"), filepath.Join(tmpDir, "next")})
+	if !errors.Is(err, errTargetsDiffer) {
+		t.Errorf("run() error = %v, want %v", err, errTargetsDiffer)
+	}
+	if want := "diverge:  after " + tmpDir; !strings.Contains(out.String(), want) {
+		t.Errorf("run() output = %q, want to contain %q", out.String(), want)
+	}
+
+	if err := run(context.Background(), &out, &errOut, []string{"diff", tmpDir}); err == nil ||
+		!strings.Contains(err.Error(), "missing PATH") {
+		t.Errorf("run() error = %v, want missing PATH", err)
+	}
+}
+
+// TestRunWithCanonicalize tests that only the canonical path is printed,
+// terminated as requested.
+func TestRunWithCanonicalize(t *testing.T) {
+	tmpDir := physicalPath(t.TempDir())
+	if err := os.Symlink("target", filepath.Join(tmpDir, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	var out, errOut bytes.Buffer
+	link := filepath.Join(tmpDir, "link")
+	if err := run(context.Background(), &out, &errOut, []string{"-f", "-z", link, link}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	want := filepath.Join(tmpDir, "target") + "\x00"
+	if out.String() != want+want {
+		t.Errorf("run() output = %q, want %q", out.String(), want+want)
+	}
+
+	if err := run(context.Background(), &out, &errOut, []string{"-e", link}); err == nil {
+		t.Error("run() error = nil, want error for missing target")
+	}
+}
+
+// TestLinksOnly tests that plain directories are left out of a chain while
+// its links, mount points, errors, findings and endpoint are kept.
+func TestLinksOnly(t *testing.T) {
+	entries := []entry{
+		{Name: "/", Dev: 1, Pdev: 1},
+		{Name: "usr", Dev: 1, Pdev: 1},
+		{Name: "lib", Dev: 1, Pdev: 1, Findings: []finding{newFinding(ruleAuditWritable, "writable")}},
+		{Name: "jvm", Dev: 2, Pdev: 1},
+		{Name: "default", Dev: 2, Pdev: 2, Link: "java-21"},
+		{Name: "java-21", Dev: 2, Pdev: 2, Level: 1},
+		{Name: "bin", Dev: 2, Pdev: 2},
+		{Name: "java", Dev: 2, Pdev: 2},
+	}
+
+	var got []string
+	for _, e := range linksOnly(entries) {
+		got = append(got, e.Name)
+	}
+	if want := []string{"lib", "jvm", "default", "java"}; !slices.Equal(got, want) {
+		t.Errorf("linksOnly() = %v, want %v", got, want)
+	}
+
+	failed := []entry{{Name: "/", Dev: 1, Pdev: 1}, {Name: "missing", Err: os.ErrNotExist}}
+	if got := linksOnly(failed); len(got) != 1 || got[0].Name != "missing" {
+		t.Errorf("linksOnly() = %v, want only the entry in error", got)
+	}
+}
+
+// TestCollectEntriesFollowModes tests which symlinks of a chain are followed
+// by each mode limiting them.
+func TestCollectEntriesFollowModes(t *testing.T) {
+	tmpDir := physicalPath(t.TempDir())
+	if err := os.MkdirAll(filepath.Join(tmpDir, "usr", "bin"), 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	for name, target := range map[string]string{
+		"bin":             "usr/bin",
+		"usr/bin/python3": "python",
+		"usr/bin/python":  "python3.12",
+	} {
+		if err := os.Symlink(target, filepath.Join(tmpDir, name)); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "usr", "bin", "python3.12"), nil, 0755); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts options
+		want []string
+	}{
+		{"all", options{}, []string{"bin", "usr", "bin", "python3", "python", "python3.12"}},
+		{"none", options{noFollow: true}, []string{"bin", "python3"}},
+		{"max depth", options{maxDepth: 1}, []string{"bin", "usr", "bin", "python3", "python"}},
+		{"all but last", options{noFollowLast: true}, []string{"bin", "usr", "bin", "python3"}},
+		{"only last", options{followLast: true}, []string{"bin", "python3", "python", "python3.12"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := collectEntries(context.Background(), filepath.Join(tmpDir, "bin", "python3"), tt.opts)
+			if err != nil {
+				t.Fatalf("collectEntries() error = %v", err)
+			}
+			// Only the entries beneath the temporary directory are compared.
+			var got []string
+			for _, e := range entries {
+				if len(e.Dest) > len(tmpDir) && strings.HasPrefix(e.Dest, tmpDir) {
+					got = append(got, e.Name)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("collectEntries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAnnotateEntries tests that details needing extra reads of each entry
+// are only looked up when requested.
+func TestAnnotateEntries(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("inode attributes require Linux")
+	}
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := collectEntries(context.Background(), path, options{})
+	if err != nil {
+		t.Fatalf("collectEntries() error = %v", err)
+	}
+	for _, e := range entries {
+		if e.Attrs != "" {
+			t.Errorf("collectEntries() %s Attrs = %q, want none unless requested", e.Name, e.Attrs)
+		}
+	}
+
+	entries, err = collectEntries(context.Background(), path, options{attrs: true})
+	if err != nil {
+		t.Fatalf("collectEntries() error = %v", err)
+	}
+	for _, e := range entries {
+		if e.Attrs == "" {
+			t.Errorf("collectEntries() %s Attrs = \"\", want flags with --attrs", e.Name)
+		}
+	}
+}
+
 // BenchmarkRun benchmarks the main run function.
 func BenchmarkRun(b *testing.B) {
 	tmpDir := b.TempDir()
@@ -820,7 +1623,7 @@ func BenchmarkCalculateWidths(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = calculateWidths(entries)
+		_ = calculateWidths(entries, options{})
 	}
 }
 
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// checkCommand is the subcommand evaluating a policy file against paths.
+const checkCommand = "check"
+
+// policy is a set of rules loaded from a YAML file given with --policy:
+//
+//	rules:
+//	  - name: ssl-owned-by-root
+//	    paths: ["/etc/ssl/**"]
+//	    owner: root
+//	  - name: releases-contained
+//	    paths: ["/srv/app/releases/**"]
+//	    beneath: /srv/app
+//	  - name: key-mode
+//	    description: Private keys are not world-readable
+//	    severity: warning
+//	    paths: ["/etc/ssl/private/*"]
+//	    max-mode: "0640"
+type policy struct {
+	Rules []*policyRule `yaml:"rules"`
+
+	// unresolved counts the paths that could not be resolved.
+	unresolved int
+}
+
+// policyRule checks every path argument matching one of its patterns (or
+// every path, if it has none). Owner and group apply to every entry walked,
+// beneath to every symlink located inside the given directory (as with
+// --beneath), and max-mode to the final target.
+type policyRule struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Severity    string   `yaml:"severity"`
+	Paths       []string `yaml:"paths"`
+	Owner       string   `yaml:"owner"`
+	Group       string   `yaml:"group"`
+	MaxMode     string   `yaml:"max-mode"`
+	Beneath     string   `yaml:"beneath"`
+
+	maxMode uint64
+
+	// checked and failed count the paths the rule applied to, and those
+	// that violated it.
+	checked, failed int
+}
+
+// loadPolicy reads and validates the policy file at name.
+func loadPolicy(name string) (*policy, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var p policy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("policy %s: %w", name, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("policy %s: %w", name, err)
+	}
+	return &p, nil
+}
+
+// validate checks that every rule is named uniquely, tests something, and
+// has well-formed values.
+func (p *policy) validate() error {
+	if len(p.Rules) == 0 {
+		return errors.New("no rules defined")
+	}
+
+	seen := map[string]bool{}
+	for i, r := range p.Rules {
+		if r == nil || r.Name == "" {
+			return fmt.Errorf("rule %d: missing name", i+1)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("rule %s: duplicate name", r.Name)
+		}
+		seen[r.Name] = true
+
+		switch r.Severity {
+		case "":
+			r.Severity = severityError
+		case severityError, severityWarning, severityNote:
+		default:
+			return fmt.Errorf("rule %s: invalid severity: %s (supported: %s, %s, %s)",
+				r.Name, r.Severity, severityError, severityWarning, severityNote)
+		}
+
+		if r.Owner == "" && r.Group == "" && r.MaxMode == "" && r.Beneath == "" {
+			return fmt.Errorf("rule %s: no condition (owner, group, max-mode, beneath)", r.Name)
+		}
+		for _, pat := range r.Paths {
+			if _, err := path.Match(strings.ReplaceAll(pat, "**", "*"), ""); err != nil {
+				return fmt.Errorf("rule %s: invalid pattern: %s", r.Name, pat)
+			}
+		}
+		if r.MaxMode != "" {
+			m, err := strconv.ParseUint(r.MaxMode, 8, 32)
+			if err != nil || m > 07777 {
+				return fmt.Errorf("rule %s: invalid max-mode: %s", r.Name, r.MaxMode)
+			}
+			r.maxMode = m
+		}
+		if r.Beneath != "" {
+			if !filepath.IsAbs(r.Beneath) {
+				return fmt.Errorf("rule %s: beneath must be absolute: %s", r.Name, r.Beneath)
+			}
+			r.Beneath = filepath.Clean(r.Beneath)
+		}
+	}
+	return nil
+}
+
+// resolve walks a path and attaches a finding to each entry violating a rule
+// that applies to it. A path that cannot be resolved is evaluated up to the
+// entry in error, which fails every rule applying to the path. Its error is
+// kept for the end of the run instead of being returned, so the remaining
+// paths are still checked.
+func (p *policy) resolve(ctx context.Context, name string, opts options) ([]entry, error) {
+	entries, walkErr := resolvePath(ctx, name, opts)
+	if len(entries) == 0 {
+		return entries, walkErr
+	}
+
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range p.Rules {
+		if !r.applies(abs) {
+			continue
+		}
+		r.checked++
+		failed := r.evaluate(entries)
+		if walkErr != nil {
+			e := &entries[len(entries)-1]
+			e.Findings = append(e.Findings, finding{r.Name, r.Severity, "cannot resolve: " + unwrapPathError(walkErr).Error()})
+			failed = true
+		}
+		if failed {
+			r.failed++
+		}
+	}
+	if walkErr != nil {
+		p.unresolved++
+	}
+	return entries, nil
+}
+
+// applies reports whether the rule checks the given absolute path.
+func (r *policyRule) applies(name string) bool {
+	if len(r.Paths) == 0 {
+		return true
+	}
+	for _, pat := range r.Paths {
+		if matchPath(pat, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate attaches a finding to each entry violating the rule, and reports
+// whether there were any.
+func (r *policyRule) evaluate(entries []entry) (failed bool) {
+	report := func(e *entry, format string, args ...any) {
+		e.Findings = append(e.Findings, finding{r.Name, r.Severity, fmt.Sprintf(format, args...)})
+		failed = true
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		if e.Err != nil || e.Info == nil {
+			continue
+		}
+		if r.Owner != "" && r.Owner != e.User && r.Owner != strconv.Itoa(e.Uid) {
+			report(e, "owned by %s, want %s", e.User, r.Owner)
+		}
+		if r.Group != "" && r.Group != e.Group && r.Group != strconv.Itoa(e.Gid) {
+			report(e, "group %s, want %s", e.Group, r.Group)
+		}
+		if r.Beneath != "" && e.Link != "" {
+			switch x, inside, err := escapeBeneath(e.Dest, e.Link, physicalPath(r.Beneath)); {
+			case !inside:
+			case err != nil:
+				report(e, "%v", err)
+			case x != nil:
+				report(e, "%s", x.describe(r.Beneath))
+			}
+		}
+	}
+
+	if e := &entries[len(entries)-1]; r.MaxMode != "" && e.Err == nil && e.Info != nil {
+		if m, err := strconv.ParseUint(e.Octal, 8, 32); err == nil && 0 != m&^r.maxMode {
+			report(e, "mode %04o exceeds %04o", m, r.maxMode)
+		}
+	}
+
+	return failed
+}
+
+// rules describes each rule of the policy.
+func (p *policy) rules() map[string]ruleInfo {
+	info := make(map[string]ruleInfo, len(p.Rules))
+	for _, r := range p.Rules {
+		info[r.Name] = ruleInfo{r.Description, r.Severity}
+	}
+	return info
+}
+
+// printSummary writes the pass or fail status of each rule.
+func (p *policy) printSummary(w io.Writer) {
+	for _, r := range p.Rules {
+		switch {
+		case r.checked == 0:
+			fmt.Fprintf(w, "SKIP %s (no paths matched)\n", r.Name)
+		case r.failed == 0:
+			fmt.Fprintf(w, "PASS %s (%d of %d paths)\n", r.Name, r.checked, r.checked)
+		default:
+			fmt.Fprintf(w, "FAIL %s (%d of %d paths)\n", r.Name, r.failed, r.checked)
+		}
+	}
+}
+
+// failed returns the number of rules violated by at least one path.
+func (p *policy) failed() (n int) {
+	for _, r := range p.Rules {
+		if r.failed > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// isBeneath reports whether the clean absolute path name is dir or lies
+// inside it.
+func isBeneath(name, dir string) bool {
+	rel, err := filepath.Rel(dir, name)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// matchPath reports whether the slash-separated name matches pattern, where
+// "**" matches any number of path components (including none) and other
+// components match as in path.Match.
+func matchPath(pattern, name string) bool {
+	pat := strings.Split(strings.Trim(filepath.ToSlash(pattern), "/"), "/")
+	seg := strings.Split(strings.Trim(filepath.ToSlash(name), "/"), "/")
+	return matchSegments(pat, seg)
+}
+
+// matchSegments matches path components against pattern components.
+func matchSegments(pat, seg []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(seg); i++ {
+				if matchSegments(pat[1:], seg[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(seg) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], seg[0]); !ok {
+			return false
+		}
+		pat, seg = pat[1:], seg[1:]
+	}
+	return len(seg) == 0
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMatchPath tests path patterns with recursive wildcards.
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"/etc/ssl/**", "/etc/ssl", true},
+		{"/etc/ssl/**", "/etc/ssl/certs/ca.pem", true},
+		{"/etc/ssl/**", "/etc/sslx/ca.pem", false},
+		{"/etc/ssl/*", "/etc/ssl/certs", true},
+		{"/etc/ssl/*", "/etc/ssl/certs/ca.pem", false},
+		{"/srv/**/current", "/srv/app/releases/current", true},
+		{"/srv/**/current", "/srv/current", true},
+		{"/srv/**/current", "/srv/app/current/bin", false},
+		{"**/*.key", "/etc/ssl/private/server.key", true},
+		{"/etc/?sl", "/etc/ssl", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchPath(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestIsBeneath tests lexical containment of paths.
+func TestIsBeneath(t *testing.T) {
+	tests := []struct {
+		name, dir string
+		want      bool
+	}{
+		{"/srv/app", "/srv/app", true},
+		{"/srv/app/releases/v1", "/srv/app", true},
+		{"/srv/application", "/srv/app", false},
+		{"/srv", "/srv/app", false},
+		{"/etc/passwd", "/srv/app", false},
+		{"/srv/app/..data", "/srv/app", true},
+	}
+
+	for _, tt := range tests {
+		if got := isBeneath(tt.name, tt.dir); got != tt.want {
+			t.Errorf("isBeneath(%q, %q) = %v, want %v", tt.name, tt.dir, got, tt.want)
+		}
+	}
+}
+
+// TestLoadPolicy tests parsing and validation of policy files.
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			content: `rules:
+  - name: owned
+    paths: ["/etc/**"]
+    owner: root
+  - name: mode
+    severity: warning
+    max-mode: "0644"
+`,
+		},
+		{
+			name:    "empty",
+			content: "",
+			wantErr: "no rules defined",
+		},
+		{
+			name:    "unknown field",
+			content: "rules:\n  - name: x\n    ownr: root\n",
+			wantErr: "field ownr not found",
+		},
+		{
+			name:    "missing name",
+			content: "rules:\n  - owner: root\n",
+			wantErr: "missing name",
+		},
+		{
+			name:    "duplicate name",
+			content: "rules:\n  - name: x\n    owner: root\n  - name: x\n    group: root\n",
+			wantErr: "duplicate name",
+		},
+		{
+			name:    "no condition",
+			content: "rules:\n  - name: x\n    paths: [\"/etc\"]\n",
+			wantErr: "no condition",
+		},
+		{
+			name:    "invalid mode",
+			content: "rules:\n  - name: x\n    max-mode: \"0999\"\n",
+			wantErr: "invalid max-mode",
+		},
+		{
+			name:    "invalid severity",
+			content: "rules:\n  - name: x\n    severity: fatal\n    owner: root\n",
+			wantErr: "invalid severity",
+		},
+		{
+			name:    "relative beneath",
+			content: "rules:\n  - name: x\n    beneath: srv/app\n",
+			wantErr: "beneath must be absolute",
+		},
+		{
+			name:    "invalid pattern",
+			content: "rules:\n  - name: x\n    paths: [\"/etc/[\"]\n    owner: root\n",
+			wantErr: "invalid pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "rules.yaml")
+			if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := loadPolicy(file)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("loadPolicy() error = %v", err)
+				}
+				if len(p.Rules) != 2 || p.Rules[1].maxMode != 0644 {
+					t.Errorf("loadPolicy() rules = %+v, want 2 rules with max-mode 0644", p.Rules)
+				}
+				if p.Rules[0].Severity != severityError || p.Rules[1].Severity != severityWarning {
+					t.Errorf("loadPolicy() severities = %s, %s, want error, warning",
+						p.Rules[0].Severity, p.Rules[1].Severity)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadPolicy() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestPolicyRuleEvaluate tests each rule condition against walked entries.
+func TestPolicyRuleEvaluate(t *testing.T) {
+	entries := func() []entry {
+		return []entry{
+			{Name: "/", Dest: "/", User: "root", Group: "root", Octal: "0755", Info: mockFileInfo{mode: fs.ModeDir | 0755}},
+			{Name: "srv", Dest: "/srv", User: "root", Group: "root", Octal: "0755", Info: mockFileInfo{mode: fs.ModeDir | 0755}},
+			{Name: "app", Dest: "/srv/app", Uid: 1000, User: "deploy", Group: "root", Octal: "0755", Info: mockFileInfo{mode: fs.ModeDir | 0755}},
+			{Name: "current", Dest: "/srv/app/current", Link: "../../etc", User: "root", Group: "root", Octal: "0777", Info: mockFileInfo{mode: fs.ModeSymlink | 0777}},
+			{Name: "etc", Dest: "/etc", User: "root", Group: "root", Octal: "0755", Info: mockFileInfo{mode: fs.ModeDir | 0755}},
+			{Name: "shadow", Dest: "/etc/shadow", User: "root", Group: "shadow", Octal: "0640", Info: mockFileInfo{mode: 0640}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		rule    policyRule
+		want    []string
+		wantMsg string
+	}{
+		{
+			name:    "owner",
+			rule:    policyRule{Name: "r", Owner: "root"},
+			want:    []string{"app"},
+			wantMsg: "owned by deploy, want root",
+		},
+		{
+			name: "owner by uid",
+			rule: policyRule{Name: "r", Owner: "0"},
+			want: []string{"app"},
+		},
+		{
+			name:    "group",
+			rule:    policyRule{Name: "r", Group: "root"},
+			want:    []string{"shadow"},
+			wantMsg: "group shadow, want root",
+		},
+		{
+			name:    "beneath",
+			rule:    policyRule{Name: "r", Beneath: "/srv/app"},
+			want:    []string{"current"},
+			wantMsg: `leaves /srv/app at ".." in ../../etc, reaching /srv`,
+		},
+		{
+			name: "beneath ignores links outside",
+			rule: policyRule{Name: "r", Beneath: "/srv/app/releases"},
+		},
+		{
+			name: "max mode satisfied",
+			rule: policyRule{Name: "r", MaxMode: "0644", maxMode: 0644},
+		},
+		{
+			name:    "max mode exceeded",
+			rule:    policyRule{Name: "r", MaxMode: "0600", maxMode: 0600},
+			want:    []string{"shadow"},
+			wantMsg: "mode 0640 exceeds 0600",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := entries()
+			failed := tt.rule.evaluate(es)
+			if failed != (len(tt.want) > 0) {
+				t.Errorf("evaluate() = %v, want %v", failed, len(tt.want) > 0)
+			}
+
+			var got, msgs []string
+			for _, e := range es {
+				for _, f := range e.Findings {
+					got = append(got, e.Name)
+					msgs = append(msgs, f.Message)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("evaluate() findings on %v, want %v", got, tt.want)
+			}
+			if tt.wantMsg != "" && !strings.Contains(strings.Join(msgs, "\n"), tt.wantMsg) {
+				t.Errorf("evaluate() messages = %q, want to contain %q", msgs, tt.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ruleRaceChange is reported by --race for entries that changed between walks.
+const ruleRaceChange = "race-change"
+
+// raceTimeFormat is the layout of the timestamps reported by --race.
+const raceTimeFormat = "15:04:05.000000"
+
+// raceRecord is a single walk of the path raced, with the time each entry
+// was observed.
+type raceRecord struct {
+	start   time.Time
+	entries []entry
+	seen    []time.Time
+}
+
+// raceIdentity is what is compared between walks to detect a changed entry.
+type raceIdentity struct {
+	dest, link string
+	dev, inode uint64
+	err        string
+}
+
+// identify returns the identity of the i-th entry of the walk, or false if
+// the walk ended before it.
+func (r *raceRecord) identify(i int) (raceIdentity, bool) {
+	if i >= len(r.entries) {
+		return raceIdentity{}, false
+	}
+	e := r.entries[i]
+	id := raceIdentity{dest: e.Dest, link: e.Link, dev: e.Dev, inode: e.Inode}
+	if e.Err != nil {
+		id.err = unwrapPathError(e.Err).Error()
+	}
+	return id, true
+}
+
+// raceEntries walks path opts.race times, in quick succession or concurrently, and
+// returns the entries of the earliest walk. A finding is attached to each
+// entry whose (dev, inode, link) identity changed between walks, once for
+// every change observed. An error is returned only if every walk failed at
+// the same entry.
+func raceEntries(ctx context.Context, path string, opts options) ([]entry, error) {
+	walks := make([]raceRecord, opts.race)
+	observe := func(r *raceRecord) {
+		r.start = time.Now()
+		// Unlike collectEntries, a missing entry is an observation rather
+		// than a reason to stop racing.
+		_ = walk(ctx, path, func(ctx context.Context, e entry) (bool, error) {
+			r.entries = append(r.entries, e)
+			r.seen = append(r.seen, time.Now())
+			if e.Err != nil {
+				return false, e.Err
+			}
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			return opts.follows(path, e), nil
+		})
+	}
+
+	if opts.concurrent {
+		var wg sync.WaitGroup
+		for i := range walks {
+			wg.Go(func() { observe(&walks[i]) })
+		}
+		wg.Wait()
+		slices.SortStableFunc(walks, func(a, b raceRecord) int { return a.start.Compare(b.start) })
+	} else {
+		for i := range walks {
+			observe(&walks[i])
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	entries := walks[0].entries
+	annotateEntries(entries, opts)
+	raceFindings(entries, walks)
+	return entries, sameFailure(walks)
+}
+
+// sameFailure returns the error every walk ended with, if each ended at the
+// same entry with the same error. A walk that failed only some of the time
+// is reported by its findings instead.
+func sameFailure(walks []raceRecord) error {
+	n := len(walks[0].entries)
+	if n == 0 || walks[0].entries[n-1].Err == nil {
+		return nil
+	}
+	id, _ := walks[0].identify(n - 1)
+	for k := 1; k < len(walks); k++ {
+		if other, ok := walks[k].identify(n - 1); !ok || other != id || len(walks[k].entries) != n {
+			return nil
+		}
+	}
+	return walks[0].entries[n-1].Err
+}
+
+// raceFindings compares each walk after the first with the entries of the
+// first. Each position reports every time its identity differs from the one
+// last observed there. Once a walk diverges from the first, the positions
+// that follow are a consequence of the divergence and are not compared.
+func raceFindings(entries []entry, walks []raceRecord) {
+	last := make([]raceIdentity, len(entries))
+	for i := range entries {
+		last[i], _ = walks[0].identify(i)
+	}
+
+	for k := 1; k < len(walks); k++ {
+		w := &walks[k]
+		for i := range entries {
+			id, ok := w.identify(i)
+			if !ok {
+				at := w.seen[len(w.seen)-1]
+				entries[i].Findings = append(entries[i].Findings, raceFinding(k, at, "ended before this entry"))
+				break
+			}
+			if id != last[i] {
+				entries[i].Findings = append(entries[i].Findings, raceFinding(k, w.seen[i], id.describe(last[i])))
+				last[i] = id
+			}
+			if id.dest != entries[i].Dest || id.err != "" {
+				break
+			}
+			if i == len(entries)-1 && len(w.entries) > len(entries) {
+				extra := w.entries[len(entries)]
+				entries[i].Findings = append(entries[i].Findings,
+					raceFinding(k, w.seen[len(entries)], "continued to "+extra.Dest))
+			}
+		}
+	}
+}
+
+// raceFinding returns a finding of a change observed by walk k (counted from
+// zero) at the given time.
+func raceFinding(k int, at time.Time, change string) finding {
+	return newFinding(ruleRaceChange, fmt.Sprintf("walk %d at %s %s", k+1, at.Format(raceTimeFormat), change))
+}
+
+// describe explains how id differs from was, the identity observed before.
+func (id raceIdentity) describe(was raceIdentity) string {
+	switch {
+	case id.err != "":
+		return "failed: " + id.err
+	case id.dest != was.dest:
+		return fmt.Sprintf("resolved through %s, was %s", id.dest, was.dest)
+	case was.err != "":
+		return "recovered from: " + was.err
+	}
+	var change []string
+	if id.link != was.link {
+		change = append(change, fmt.Sprintf("link %q, was %q", id.link, was.link))
+	}
+	if id.dev != was.dev {
+		change = append(change, fmt.Sprintf("device %d, was %d", id.dev, was.dev))
+	}
+	if id.inode != was.inode {
+		change = append(change, fmt.Sprintf("inode %d, was %d", id.inode, was.inode))
+	}
+	return "saw " + strings.Join(change, "; ")
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRaceFindings tests that each change between walks is reported once, at
+// the entry whose identity changed.
+func TestRaceFindings(t *testing.T) {
+	stable := []entry{
+		{Dest: "/srv", Inode: 10},
+		{Dest: "/srv/current", Link: "v1", Inode: 11},
+		{Dest: "/srv/v1", Inode: 12, Level: 1},
+	}
+	retargeted := []entry{
+		{Dest: "/srv", Inode: 10},
+		{Dest: "/srv/current", Link: "v2", Inode: 13},
+		{Dest: "/srv/v2", Inode: 14, Level: 1},
+	}
+	missing := []entry{
+		{Dest: "/srv", Inode: 10},
+		{Dest: "/srv/current", Err: &os.PathError{Op: "lstat", Path: "/srv/current", Err: os.ErrNotExist}},
+	}
+	record := func(entries ...entry) raceRecord {
+		seen := make([]time.Time, len(entries))
+		return raceRecord{entries: entries, seen: seen}
+	}
+
+	tests := []struct {
+		name  string
+		walks []raceRecord
+		want  []string // findings, as "index:message suffix"
+	}{
+		{
+			name:  "unchanged",
+			walks: []raceRecord{record(stable...), record(stable...), record(stable...)},
+		},
+		{
+			name:  "retargeted",
+			walks: []raceRecord{record(stable...), record(retargeted...), record(retargeted...)},
+			want: []string{
+				`1:saw link "v2", was "v1"; inode 13, was 11`,
+				"2:resolved through /srv/v2, was /srv/v1",
+			},
+		},
+		{
+			name:  "flipped back",
+			walks: []raceRecord{record(stable...), record(retargeted...), record(stable...)},
+			want: []string{
+				`1:saw link "v2", was "v1"; inode 13, was 11`,
+				`1:saw link "v1", was "v2"; inode 11, was 13`,
+				"2:resolved through /srv/v2, was /srv/v1",
+				"2:resolved through /srv/v1, was /srv/v2",
+			},
+		},
+		{
+			name:  "briefly missing",
+			walks: []raceRecord{record(stable...), record(missing...), record(stable...)},
+			want: []string{
+				"1:failed: file does not exist",
+				"1:recovered from: file does not exist",
+			},
+		},
+		{
+			name:  "first walk missing",
+			walks: []raceRecord{record(missing...), record(stable...)},
+			want: []string{
+				"1:recovered from: file does not exist",
+				"1:continued to /srv/v1",
+			},
+		},
+		{
+			name:  "ended early",
+			walks: []raceRecord{record(stable...), record(stable[:2]...)},
+			want:  []string{"2:ended before this entry"},
+		},
+		{
+			name:  "continued",
+			walks: []raceRecord{record(stable...), record(append(stable[:3:3], entry{Dest: "/srv/v1/next", Level: 1})...)},
+			want:  []string{"2:continued to /srv/v1/next"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := append([]entry(nil), tt.walks[0].entries...)
+			raceFindings(entries, tt.walks)
+
+			var got []string
+			for i, e := range entries {
+				for _, f := range e.Findings {
+					if f.Rule != ruleRaceChange {
+						t.Errorf("raceFindings() rule = %q, want %q", f.Rule, ruleRaceChange)
+					}
+					// Drop the walk number and timestamp.
+					_, msg, _ := strings.Cut(f.Message, "000000 ")
+					got = append(got, strconv.Itoa(i)+":"+msg)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("raceFindings() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSameFailure tests that an error is returned only if every walk failed
+// at the same entry in the same way.
+func TestSameFailure(t *testing.T) {
+	ok := []entry{{Dest: "/srv", Inode: 10}}
+	notExist := []entry{{Dest: "/srv", Err: &os.PathError{Op: "lstat", Path: "/srv", Err: os.ErrNotExist}}}
+	denied := []entry{{Dest: "/srv", Err: &os.PathError{Op: "lstat", Path: "/srv", Err: os.ErrPermission}}}
+
+	tests := []struct {
+		name    string
+		walks   [][]entry
+		wantErr bool
+	}{
+		{name: "all succeeded", walks: [][]entry{ok, ok}},
+		{name: "all failed", walks: [][]entry{notExist, notExist, notExist}, wantErr: true},
+		{name: "first failed", walks: [][]entry{notExist, ok}},
+		{name: "later failed", walks: [][]entry{ok, notExist}},
+		{name: "failed differently", walks: [][]entry{notExist, denied}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			walks := make([]raceRecord, len(tt.walks))
+			for i, entries := range tt.walks {
+				walks[i].entries = entries
+			}
+			if err := sameFailure(walks); (err != nil) != tt.wantErr {
+				t.Errorf("sameFailure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestRaceEntries tests that a path left alone does not change between
+// walks, whether or not they run concurrently.
+func TestRaceEntries(t *testing.T) {
+	dir := t.TempDir()
+	link := filepath.Join(dir, "current")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, concurrent := range []bool{false, true} {
+		entries, err := raceEntries(context.Background(), link, options{race: 10, concurrent: concurrent})
+		if err != nil {
+			t.Fatalf("raceEntries() error = %v", err)
+		}
+		if n := countFindings(entries); n != 0 {
+			t.Errorf("raceEntries(concurrent=%v) findings = %d, want 0", concurrent, n)
+		}
+	}
+
+	if _, err := raceEntries(context.Background(), filepath.Join(dir, "missing"), options{race: 2}); err == nil {
+		t.Error("raceEntries() error = nil, want error for missing path")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// refsCommand is the subcommand finding the symlinks that resolve through a
+// target.
+const refsCommand = "refs"
+
+// ruleRefsTarget is reported by refs where a chain reaches the target.
+const ruleRefsTarget = "refs-target"
+
+// refsRules are the rules counted in the summary of refs.
+var refsRules = []string{ruleRefsTarget}
+
+// fileID identifies a file by device and inode.
+type fileID struct {
+	dev, inode uint64
+}
+
+// findRefs checks every symlink in the directory trees of opts.in (or "/")
+// for a chain that passes through target, attaching a finding to the entry
+// at which each chain reaches it. Both target itself and, if it is a
+// symlink, the file it resolves to are matched.
+func findRefs(ctx context.Context, errOut io.Writer, target string, opts options) (*scanResult, error) {
+	ids := map[fileID]bool{}
+	for _, stat := range []func(string) (os.FileInfo, error){os.Lstat, os.Stat} {
+		info, err := stat(target)
+		if err != nil {
+			return nil, err
+		}
+		dev, inode, _ := getDeviceInfo(info)
+		if inode == 0 {
+			return nil, fmt.Errorf("%s: %s: files cannot be identified on this platform", refsCommand, target)
+		}
+		ids[fileID{dev, inode}] = true
+	}
+
+	dirs := splitList(opts.in)
+	if len(dirs) == 0 {
+		dirs = []string{"/"}
+	}
+	return findLinks(ctx, errOut, dirs, opts, refsRules, func(ctx context.Context, job scanJob) []entry {
+		return refLink(ctx, job, target, ids)
+	})
+}
+
+// refLink walks the symlink of job and attaches a finding to the first entry
+// of its resolution identified by ids. The entries of the path to the
+// symlink itself are not considered.
+func refLink(ctx context.Context, job scanJob, target string, ids map[fileID]bool) []entry {
+	entries, _ := collectEntries(ctx, job.path, options{})
+	for i := range entries {
+		e := &entries[i]
+		if e.Level > 0 && e.Err == nil && ids[fileID{e.Dev, e.Inode}] {
+			e.Findings = append(e.Findings, newFinding(ruleRefsTarget, "resolves through "+target))
+			break
+		}
+	}
+	return entries
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// TestFindRefs tests that only symlinks resolving through the target are
+// reported, at the entry where they reach it.
+func TestFindRefs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("files have no inode on Windows")
+	}
+
+	base := physicalPath(t.TempDir())
+	for _, dir := range []string{"opt/toolchain-12/bin", "opt/toolchain-13/bin", "usr/bin", "usr/skip"} {
+		if err := os.MkdirAll(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range map[string]string{
+		"opt/current": "toolchain-12",
+		"opt/next":    "toolchain-13",
+		"usr/bin/cc":  "../../opt/current/bin",
+		"usr/bin/new": "../../opt/next/bin",
+		"usr/bin/opt": "../../opt",
+		"usr/skip/cc": "../../opt/toolchain-12",
+	} {
+		if err := os.Symlink(target, filepath.Join(base, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	target := filepath.Join(base, "opt", "toolchain-12")
+	opts := options{in: filepath.Join(base, "usr"), exclude: "skip"}
+	res, err := findRefs(context.Background(), io.Discard, target, opts)
+	if err != nil {
+		t.Fatalf("findRefs() error = %v", err)
+	}
+	if want := []string{filepath.Join(base, "usr", "bin", "cc")}; !slices.Equal(res.paths, want) {
+		t.Fatalf("findRefs() paths = %v, want %v", res.paths, want)
+	}
+	for _, e := range res.chains[res.paths[0]] {
+		if len(e.Findings) > 0 && e.Dest != target {
+			t.Errorf("findRefs() finding at %s, want at %s", e.Dest, target)
+		}
+	}
+
+	// The symlink to the target is matched as well as the target itself.
+	res, err = findRefs(context.Background(), io.Discard, filepath.Join(base, "opt", "current"), opts)
+	if err != nil {
+		t.Fatalf("findRefs() error = %v", err)
+	}
+	if res.found[ruleRefsTarget] != 1 {
+		t.Errorf("findRefs() found %d refs through symlink, want 1", res.found[ruleRefsTarget])
+	}
+
+	if _, err := findRefs(context.Background(), io.Discard, filepath.Join(base, "missing"), opts); err == nil {
+		t.Error("findRefs() error = nil, want error for missing target")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// SARIF log identification (Static Analysis Results Interchange Format).
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifInfoURI = "https://github.com/ardnew/lsi"
+)
+
+// The subset of the SARIF 2.1.0 object model needed to report findings.
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+
+	sarifRule struct {
+		ID                   string             `json:"id"`
+		ShortDescription     sarifMessage       `json:"shortDescription"`
+		DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	}
+
+	sarifConfiguration struct {
+		Level string `json:"level"`
+	}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		RuleIndex int             `json:"ruleIndex"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+		CodeFlows []sarifCodeFlow `json:"codeFlows"`
+	}
+
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+		Message          *sarifMessage         `json:"message,omitempty"`
+	}
+
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	}
+
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+
+	sarifCodeFlow struct {
+		Message     sarifMessage      `json:"message"`
+		ThreadFlows []sarifThreadFlow `json:"threadFlows"`
+	}
+
+	sarifThreadFlow struct {
+		Locations []sarifThreadFlowLocation `json:"locations"`
+	}
+
+	sarifThreadFlowLocation struct {
+		Location     sarifLocation `json:"location"`
+		NestingLevel int           `json:"nestingLevel"`
+		Importance   string        `json:"importance"`
+	}
+)
+
+// printSARIF walks each path and writes every finding as a SARIF result,
+// located at the entry responsible, with the resolution chain of its path as
+// a code flow. Rules are described from the given set. A walk that failed
+// contributes the findings up to the entry in error, and the first such
+// error is returned once the log is written.
+func printSARIF(ctx context.Context, out io.Writer, paths []string, opts options, resolve resolveFunc, rules map[string]ruleInfo) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           command,
+			Version:        getVersion(),
+			InformationURI: sarifInfoURI,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	index := map[string]int{}
+
+	var walkErr error
+	for _, p := range paths {
+		entries, err := resolve(ctx, p, opts)
+		if err != nil {
+			if len(entries) == 0 {
+				return err
+			}
+			if walkErr == nil {
+				walkErr = err
+			}
+		}
+
+		for i, e := range entries {
+			for _, f := range e.Findings {
+				n, ok := index[f.Rule]
+				if !ok {
+					n = len(run.Tool.Driver.Rules)
+					index[f.Rule] = n
+					run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRuleFor(f, rules))
+				}
+				run.Results = append(run.Results, sarifResult{
+					RuleID:    f.Rule,
+					RuleIndex: n,
+					Level:     f.Severity,
+					Message:   sarifMessage{e.Name + ": " + f.Message},
+					Locations: []sarifLocation{{PhysicalLocation: sarifPhysical(e)}},
+					CodeFlows: []sarifCodeFlow{sarifChain(p, entries, i)},
+				})
+			}
+		}
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}}); err != nil {
+		return err
+	}
+	return walkErr
+}
+
+// sarifRuleFor describes the rule of a finding, using the rule set if it
+// knows the rule.
+func sarifRuleFor(f finding, rules map[string]ruleInfo) sarifRule {
+	info, ok := rules[f.Rule]
+	if !ok || info.Description == "" {
+		info.Description = f.Rule
+	}
+	if info.Severity == "" {
+		info.Severity = f.Severity
+	}
+	return sarifRule{
+		ID:                   f.Rule,
+		ShortDescription:     sarifMessage{info.Description},
+		DefaultConfiguration: sarifConfiguration{info.Severity},
+	}
+}
+
+// sarifChain returns the entries walked for path as a code flow, marking
+// the entry at index i as the one responsible for the result.
+func sarifChain(path string, entries []entry, i int) sarifCodeFlow {
+	var flow sarifThreadFlow
+	for j, e := range entries {
+		text := e.Name
+		if e.Link != "" {
+			text += " -> " + e.Link
+		}
+		importance := "important"
+		if j == i {
+			importance = "essential"
+		}
+		flow.Locations = append(flow.Locations, sarifThreadFlowLocation{
+			Location: sarifLocation{
+				PhysicalLocation: sarifPhysical(e),
+				Message:          &sarifMessage{text},
+			},
+			NestingLevel: e.Level,
+			Importance:   importance,
+		})
+	}
+	return sarifCodeFlow{
+		Message:     sarifMessage{"resolution of " + filepath.Clean(path)},
+		ThreadFlows: []sarifThreadFlow{flow},
+	}
+}
+
+// sarifPhysical returns the location of an entry as an absolute file URI.
+func sarifPhysical(e entry) sarifPhysicalLocation {
+	name, err := filepath.Abs(e.Dest)
+	if err != nil {
+		name = e.Dest
+	}
+	name = filepath.ToSlash(name)
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	u := url.URL{Scheme: "file", Path: name}
+	return sarifPhysicalLocation{sarifArtifactLocation{u.String()}}
+}
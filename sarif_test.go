@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// TestPrintSARIF tests that findings become SARIF results with their chain.
+func TestPrintSARIF(t *testing.T) {
+	resolve := func(ctx context.Context, path string, opts options) ([]entry, error) {
+		return []entry{
+			{Name: "/", Dest: "/", Level: 0},
+			{Name: "srv", Dest: "/srv", Level: 0, Findings: []finding{
+				newFinding(ruleAuditOwner, "owned by untrusted user deploy"),
+			}},
+			{Name: "app", Dest: "/srv/app", Link: "/opt/app", Level: 0, Findings: []finding{
+				{Rule: "contained", Severity: severityNote, Message: "link to /opt/app escapes /srv"},
+				newFinding(ruleAuditOwner, "owned by untrusted user deploy"),
+			}},
+			{Name: "opt", Dest: "/opt", Level: 1},
+			{Name: "app", Dest: "/opt/app", Level: 1},
+		}, nil
+	}
+	rules := map[string]ruleInfo{
+		ruleAuditOwner: builtinRules[ruleAuditOwner],
+		"contained":    {"Links stay inside /srv", severityNote},
+	}
+
+	var out bytes.Buffer
+	if err := printSARIF(context.Background(), &out, []string{"/srv/app/"}, options{}, resolve, rules); err != nil {
+		t.Fatalf("printSARIF() error = %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("printSARIF() output is not valid JSON: %v", err)
+	}
+	if log.Version != sarifVersion || len(log.Runs) != 1 {
+		t.Fatalf("printSARIF() version = %q with %d runs, want %q with 1 run", log.Version, len(log.Runs), sarifVersion)
+	}
+
+	run := log.Runs[0]
+	if n := len(run.Tool.Driver.Rules); n != 2 {
+		t.Fatalf("printSARIF() described %d rules, want 2", n)
+	}
+	if r := run.Tool.Driver.Rules[1]; r.ID != "contained" || r.ShortDescription.Text != "Links stay inside /srv" ||
+		r.DefaultConfiguration.Level != severityNote {
+		t.Errorf("printSARIF() rule = %+v, want policy rule description and level", r)
+	}
+
+	if n := len(run.Results); n != 3 {
+		t.Fatalf("printSARIF() reported %d results, want 3", n)
+	}
+	res := run.Results[1]
+	if res.RuleID != "contained" || res.RuleIndex != 1 || res.Level != severityNote {
+		t.Errorf("printSARIF() result = %s/%d/%s, want contained/1/note", res.RuleID, res.RuleIndex, res.Level)
+	}
+	if uri := res.Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "file:///srv/app" {
+		t.Errorf("printSARIF() location = %q, want file:///srv/app", uri)
+	}
+	if run.Results[2].RuleIndex != 0 {
+		t.Errorf("printSARIF() second audit-owner result has rule index %d, want 0", run.Results[2].RuleIndex)
+	}
+
+	flow := res.CodeFlows[0]
+	if flow.Message.Text != "resolution of /srv/app" {
+		t.Errorf("printSARIF() code flow message = %q", flow.Message.Text)
+	}
+	locs := flow.ThreadFlows[0].Locations
+	if len(locs) != 5 {
+		t.Fatalf("printSARIF() code flow has %d locations, want 5", len(locs))
+	}
+	if l := locs[2]; l.Importance != "essential" || l.Location.Message.Text != "app -> /opt/app" {
+		t.Errorf("printSARIF() responsible location = %+v, want essential app -> /opt/app", l)
+	}
+	if l := locs[4]; l.Importance != "important" || l.NestingLevel != 1 {
+		t.Errorf("printSARIF() last location = %+v, want important at nesting level 1", l)
+	}
+}
+
+// TestPrintSARIFEmpty tests that a run without findings has empty results.
+func TestPrintSARIFEmpty(t *testing.T) {
+	resolve := func(ctx context.Context, path string, opts options) ([]entry, error) {
+		return []entry{{Name: "/", Dest: "/"}}, nil
+	}
+
+	var out bytes.Buffer
+	if err := printSARIF(context.Background(), &out, []string{"/"}, options{}, resolve, builtinRules); err != nil {
+		t.Fatalf("printSARIF() error = %v", err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte(`"results": []`)) || !bytes.Contains(out.Bytes(), []byte(`"rules": []`)) {
+		t.Errorf("printSARIF() output = %s, want empty rules and results", out.String())
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scanCommand is the subcommand checking every symlink in directory trees.
+const scanCommand = "scan"
+
+// defaultMaxHops is the number of links a chain may pass through before scan
+// reports it, unless --max-hops is given.
+const defaultMaxHops = 8
+
+// Rules reported by scan for the symlinks it finds.
+const (
+	ruleScanDangling   = "scan-dangling"
+	ruleScanLoop       = "scan-loop"
+	ruleScanLongChain  = "scan-long-chain"
+	ruleScanCrossMount = "scan-cross-mount"
+)
+
+// scanRules are the rules counted in the summary of scan, in order.
+var scanRules = []string{ruleScanDangling, ruleScanLoop, ruleScanLongChain, ruleScanCrossMount, ruleBeneathEscape}
+
+// scanResult holds the symlinks found in a set of directory trees and the
+// chain walked from each symlink with findings.
+type scanResult struct {
+	dirs, links int
+	chains      map[string][]entry
+	found       map[string]int
+
+	// rules lists the rules counted in the summary, in order.
+	rules []string
+
+	// paths lists the symlinks with findings, sorted.
+	paths []string
+}
+
+// scanJob is a symlink to check, found in the tree rooted at dir.
+type scanJob struct {
+	path, dir, root string
+}
+
+// checkLinkFunc walks the symlink of a job and returns its chain, with
+// findings attached to its entries.
+type checkLinkFunc func(ctx context.Context, job scanJob) []entry
+
+// scanTrees checks every symlink in the given directory trees for problems
+// with its resolution.
+func scanTrees(ctx context.Context, errOut io.Writer, dirs []string, opts options) (*scanResult, error) {
+	maxHops := opts.maxHops
+	if maxHops == 0 {
+		maxHops = defaultMaxHops
+	}
+	return findLinks(ctx, errOut, dirs, opts, scanRules, func(ctx context.Context, job scanJob) []entry {
+		return scanLink(ctx, job, maxHops)
+	})
+}
+
+// findLinks walks each directory tree without following symlinks, and checks
+// every symlink found using up to opts.jobs workers. Paths matching
+// opts.exclude are skipped, as are other filesystems with
+// opts.oneFileSys. Directories within the trees that cannot be read are
+// reported to errOut and skipped.
+func findLinks(ctx context.Context, errOut io.Writer, dirs []string, opts options, rules []string, check checkLinkFunc) (*scanResult, error) {
+	start := time.Now()
+	res := &scanResult{chains: map[string][]entry{}, found: map[string]int{}, rules: rules}
+
+	jobs := opts.jobs
+	if jobs == 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	exclude := splitList(opts.exclude)
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		queue = make(chan scanJob)
+	)
+	for range jobs {
+		wg.Go(func() {
+			for j := range queue {
+				entries := check(ctx, j)
+				if n := countFindings(entries); n > 0 {
+					mu.Lock()
+					res.chains[j.path] = entries
+					for _, e := range entries {
+						for _, f := range e.Findings {
+							res.found[f.Rule]++
+						}
+					}
+					mu.Unlock()
+				}
+			}
+		})
+	}
+
+	var err error
+	for _, dir := range dirs {
+		var info os.FileInfo
+		if info, err = os.Lstat(dir); err != nil {
+			break
+		}
+		dev, _, _ := getDeviceInfo(info)
+		var root string
+		if root, err = filepath.Abs(dir); err != nil {
+			break
+		}
+		root = physicalPath(root)
+		err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				if path == dir {
+					return err
+				}
+				fmt.Fprintf(errOut, "%s: %v\n", command, err)
+				return nil
+			}
+			if path != dir && excluded(path, exclude) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			switch {
+			case d.IsDir():
+				if opts.oneFileSys && path != dir {
+					if info, err := d.Info(); err == nil {
+						if sub, _, _ := getDeviceInfo(info); sub != dev {
+							return fs.SkipDir
+						}
+					}
+				}
+				res.dirs++
+			case 0 != d.Type()&fs.ModeSymlink:
+				res.links++
+				queue <- scanJob{path, dir, root}
+			}
+			return nil
+		})
+		if err != nil {
+			break
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, contextError(ctx, start)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for p := range res.chains {
+		res.paths = append(res.paths, p)
+	}
+	slices.Sort(res.paths)
+	return res, nil
+}
+
+// splitList returns the non-empty elements of a comma-separated list.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// excluded reports whether name matches one of the patterns. A pattern
+// containing a separator is matched against the whole name, as by matchPath,
+// and any other pattern against its base name.
+func excluded(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if !strings.ContainsRune(filepath.ToSlash(p), '/') {
+			if ok, _ := filepath.Match(p, filepath.Base(name)); ok {
+				return true
+			}
+		} else if matchPath(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanLink walks the symlink of job and attaches a finding to it for each
+// problem with its resolution.
+func scanLink(ctx context.Context, job scanJob, maxHops int) []entry {
+	entries, err := collectEntries(ctx, job.path, options{})
+	if ctx.Err() != nil || len(entries) == 0 {
+		return nil
+	}
+	var revisited string
+	if errors.Is(err, errSymlinkLoop) {
+		entries, revisited = trimLoop(entries)
+	}
+
+	// The symlink is the last entry of the path itself, ahead of the
+	// entries of its target.
+	i := len(entries) - 1
+	for i > 0 && entries[i].Level > 0 {
+		i--
+	}
+	link, last := &entries[i], entries[len(entries)-1]
+	report := func(rule, message string) {
+		link.Findings = append(link.Findings, newFinding(rule, message))
+	}
+
+	switch {
+	case revisited != "":
+		report(ruleScanLoop, "loops back to "+revisited)
+	case errors.Is(err, errSymlinkLoop):
+		report(ruleScanLoop, fmt.Sprintf("more than %d links followed", maxSymlinks))
+	case err != nil:
+		report(ruleScanDangling, fmt.Sprintf("%s: %v", last.Dest, unwrapPathError(last.Err)))
+	default:
+		var hops int
+		for _, e := range entries {
+			if e.Link != "" {
+				hops++
+			}
+		}
+		if hops > maxHops {
+			report(ruleScanLongChain, fmt.Sprintf("resolves through %d links, more than %d", hops, maxHops))
+		}
+		if last.Dev != link.Dev {
+			report(ruleScanCrossMount, fmt.Sprintf("resolves to %s on device %d from device %d", last.Dest, last.Dev, link.Dev))
+		}
+	}
+
+	if x, _, err := escapeBeneath(link.Dest, link.Link, job.root); err == nil && x != nil {
+		report(ruleBeneathEscape, x.describe(job.dir))
+	}
+	return entries
+}
+
+// trimLoop cuts a chain that ended in a symlink loop after the first link
+// visited twice, keeping the entry in error that ended it, and returns the
+// path of that link. A chain that is long but never revisits a link is
+// returned as is.
+func trimLoop(entries []entry) ([]entry, string) {
+	seen := map[string]bool{}
+	end := entries[len(entries)-1]
+	for j, e := range entries[:len(entries)-1] {
+		if e.Link == "" {
+			continue
+		}
+		if seen[e.Dest] {
+			end.Level = e.Level + 1
+			return append(entries[:j+1:j+1], end), e.Dest
+		}
+		seen[e.Dest] = true
+	}
+	return entries, ""
+}
+
+// resolve returns the chain walked from a symlink with findings.
+func (r *scanResult) resolve(ctx context.Context, path string, opts options) ([]entry, error) {
+	return r.chains[path], nil
+}
+
+// printSummary prints the number of symlinks scanned and found by each rule.
+func (r *scanResult) printSummary(w io.Writer) {
+	fmt.Fprintf(w, "Scanned %d symlinks in %d directories\n", r.links, r.dirs)
+	width := len(fmt.Sprint(r.links))
+	for _, rule := range r.rules {
+		fmt.Fprintf(w, "  %*d %s\n", width, r.found[rule], rule)
+	}
+}
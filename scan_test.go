@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestScanTrees tests that each kind of broken symlink is found and counted.
+func TestScanTrees(t *testing.T) {
+	base := physicalPath(t.TempDir())
+	tree := filepath.Join(base, "tree")
+	if err := os.MkdirAll(filepath.Join(tree, "sub", "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{
+		"ok":       "sub/lib",
+		"dangling": "missing",
+		"loop-a":   "loop-b",
+		"loop-b":   "loop-a",
+		"escape":   "..",
+		"sub/c1":   "c2",
+		"sub/c2":   "c3",
+		"sub/c3":   "lib",
+		"abs":      tree + "/sub/lib",
+		"abs-out":  base,
+	} {
+		if err := os.Symlink(target, filepath.Join(tree, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := scanTrees(context.Background(), io.Discard, []string{tree}, options{jobs: 2, maxHops: 2})
+	if err != nil {
+		t.Fatalf("scanTrees() error = %v", err)
+	}
+
+	if res.links != 10 || res.dirs != 3 {
+		t.Errorf("scanTrees() scanned %d links in %d dirs, want 10 in 3", res.links, res.dirs)
+	}
+	want := map[string]int{
+		ruleScanDangling:  1,
+		ruleScanLoop:      2,
+		ruleScanLongChain: 1,
+		ruleBeneathEscape: 2,
+	}
+	for _, rule := range scanRules {
+		if res.found[rule] != want[rule] {
+			t.Errorf("scanTrees() found %d %s, want %d", res.found[rule], rule, want[rule])
+		}
+	}
+	var paths []string
+	// An absolute target counts as escaping only if it resolves outside.
+	for _, name := range []string{"abs-out", "dangling", "escape", "loop-a", "loop-b", "sub/c1"} {
+		paths = append(paths, filepath.Join(tree, name))
+	}
+	if !slices.Equal(res.paths, paths) {
+		t.Errorf("scanTrees() paths = %v, want %v", res.paths, paths)
+	}
+
+	// A looping chain ends just after the link it revisits.
+	loop := res.chains[filepath.Join(tree, "loop-a")]
+	if got := loop[len(loop)-2].Dest; got != filepath.Join(tree, "loop-a") {
+		t.Errorf("scanTrees() loop ends after %s, want loop-a", got)
+	}
+
+	if _, err := scanTrees(context.Background(), io.Discard, []string{filepath.Join(base, "missing")}, options{}); err == nil {
+		t.Error("scanTrees() error = nil, want error for missing DIR")
+	}
+}
+
+// TestScanSummary tests the counts printed after scanning.
+func TestScanSummary(t *testing.T) {
+	res := &scanResult{dirs: 3, links: 12, found: map[string]int{ruleScanDangling: 2, ruleBeneathEscape: 1}, rules: scanRules}
+
+	var out bytes.Buffer
+	res.printSummary(&out)
+	want := `Scanned 12 symlinks in 3 directories
+   2 scan-dangling
+   0 scan-loop
+   0 scan-long-chain
+   0 scan-cross-mount
+   1 beneath-escape
+`
+	if out.String() != want {
+		t.Errorf("printSummary() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+// TestExcluded tests matching of --exclude patterns.
+func TestExcluded(t *testing.T) {
+	patterns := []string{"node_modules", "*.bak", "/proc", "/srv/**/cache"}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"/srv/app/node_modules", true},
+		{"/srv/app/config.bak", true},
+		{"/proc", true},
+		{"/proc/self", false},
+		{"/srv/app/v1/cache", true},
+		{"/srv/cache", true},
+		{"/srv/app/current", false},
+	}
+
+	for _, tt := range tests {
+		if got := excluded(tt.name, patterns); got != tt.want {
+			t.Errorf("excluded(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Subcommands recording a baseline of resolved paths and comparing against it.
+const (
+	snapshotCommand = "snapshot"
+	verifyCommand   = "verify"
+)
+
+// baselineVersion is the format version written by snapshot.
+const baselineVersion = 1
+
+// Rules reported by verify for entries that differ from the baseline.
+const (
+	ruleDriftChain  = "drift-chain"
+	ruleDriftLink   = "drift-link"
+	ruleDriftInode  = "drift-inode"
+	ruleDriftDevice = "drift-device"
+	ruleDriftMount  = "drift-mount"
+	ruleDriftOwner  = "drift-owner"
+	ruleDriftMode   = "drift-mode"
+)
+
+// baseline is the document written by snapshot and read by verify.
+type baseline struct {
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	NoFollow bool      `json:"noFollow,omitempty"`
+
+	// MaxDepth, NoFollowLast and FollowLast record the other options
+	// limiting which symlinks were followed.
+	MaxDepth     int  `json:"maxDepth,omitempty"`
+	NoFollowLast bool `json:"noFollowLast,omitempty"`
+	FollowLast   bool `json:"followLast,omitempty"`
+
+	Paths []snapshotPath `json:"paths"`
+
+	// index maps each recorded path to its entries.
+	index map[string][]snapshotEntry
+}
+
+// snapshotPath records the resolution chain of a single absolute path.
+type snapshotPath struct {
+	Path    string          `json:"path"`
+	Entries []snapshotEntry `json:"entries"`
+}
+
+// snapshotEntry records the identity of a single entry: what it is, where it
+// points, and who controls it.
+type snapshotEntry struct {
+	Dest  string `json:"dest"`
+	Link  string `json:"link,omitempty"`
+	Mode  string `json:"mode"`
+	Dev   uint64 `json:"dev"`
+	Pdev  uint64 `json:"pdev"`
+	Inode uint64 `json:"inode"`
+	Uid   int    `json:"uid"`
+	User  string `json:"user"`
+	Gid   int    `json:"gid"`
+	Group string `json:"group"`
+	Level int    `json:"level"`
+}
+
+// makeSnapshotEntry returns the recorded identity of e.
+func makeSnapshotEntry(e entry) snapshotEntry {
+	return snapshotEntry{
+		Dest:  e.Dest,
+		Link:  e.Link,
+		Mode:  e.Mode,
+		Dev:   e.Dev,
+		Pdev:  e.Pdev,
+		Inode: e.Inode,
+		Uid:   e.Uid,
+		User:  e.User,
+		Gid:   e.Gid,
+		Group: e.Group,
+		Level: e.Level,
+	}
+}
+
+// snapshotEntries returns the recorded identity of each entry.
+func snapshotEntries(entries []entry) []snapshotEntry {
+	rec := make([]snapshotEntry, 0, len(entries))
+	for _, e := range entries {
+		rec = append(rec, makeSnapshotEntry(e))
+	}
+	return rec
+}
+
+// printSnapshot walks each path, made absolute so that the baseline can be
+// verified from any directory, and writes the baseline as JSON.
+func printSnapshot(ctx context.Context, out io.Writer, paths []string, opts options) error {
+	b := baseline{
+		Version:  baselineVersion,
+		Created:  time.Now().UTC().Truncate(time.Second),
+		NoFollow: opts.noFollow,
+
+		MaxDepth:     opts.maxDepth,
+		NoFollowLast: opts.noFollowLast,
+		FollowLast:   opts.followLast,
+
+		Paths: make([]snapshotPath, 0, len(paths)),
+	}
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		entries, err := resolvePath(ctx, abs, opts)
+		if err != nil {
+			return err
+		}
+		b.Paths = append(b.Paths, snapshotPath{Path: abs, Entries: snapshotEntries(entries)})
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// loadBaseline reads and validates the baseline file at name.
+func loadBaseline(name string) (*baseline, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var b baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("baseline %s: %w", name, err)
+	}
+	if b.Version != baselineVersion {
+		return nil, fmt.Errorf("baseline %s: unsupported version %d", name, b.Version)
+	}
+	if len(b.Paths) == 0 {
+		return nil, fmt.Errorf("baseline %s: no paths recorded", name)
+	}
+
+	b.index = make(map[string][]snapshotEntry, len(b.Paths))
+	for _, p := range b.Paths {
+		b.index[p.Path] = p.Entries
+	}
+	return &b, nil
+}
+
+// paths returns the recorded paths in the order they were snapshotted.
+func (b *baseline) paths() []string {
+	paths := make([]string, 0, len(b.Paths))
+	for _, p := range b.Paths {
+		paths = append(paths, p.Path)
+	}
+	return paths
+}
+
+// resolve walks a recorded path and attaches a finding to each entry that
+// differs from the baseline. An entry that can no longer be walked is drift
+// like any other, so the walk up to it is compared rather than failing.
+func (b *baseline) resolve(ctx context.Context, path string, opts options) ([]entry, error) {
+	entries, err := resolvePath(ctx, path, opts)
+	if len(entries) == 0 {
+		return nil, err
+	}
+	driftEntries(entries, b.index[path])
+	return entries, nil
+}
+
+// driftEntries compares entries with the recorded chain base, position by
+// position. Once the chain diverges from the baseline, the entries that
+// follow are a consequence of the divergence and are not compared.
+func driftEntries(entries []entry, base []snapshotEntry) {
+	for i := range entries {
+		e := &entries[i]
+		if i >= len(base) {
+			e.Findings = append(e.Findings, newFinding(ruleDriftChain, "not in baseline"))
+			return
+		}
+		r := base[i]
+		if e.Dest != r.Dest || e.Level != r.Level {
+			e.Findings = append(e.Findings, newFinding(ruleDriftChain,
+				fmt.Sprintf("resolves through %s, was %s", e.Dest, r.Dest)))
+			return
+		}
+		if e.Err != nil {
+			e.Findings = append(e.Findings, newFinding(ruleDriftChain, missingReason(e.Err)))
+			return
+		}
+		e.Findings = append(e.Findings, driftFindings(e, r)...)
+	}
+	if n := len(entries); n > 0 && n < len(base) {
+		e := &entries[n-1]
+		e.Findings = append(e.Findings, newFinding(ruleDriftChain,
+			fmt.Sprintf("chain ends here, was followed by %s", base[n].Dest)))
+	}
+}
+
+// missingReason describes why a recorded entry could not be walked.
+func missingReason(err error) string {
+	if errors.Is(err, fs.ErrNotExist) {
+		return "missing"
+	}
+	return "cannot be walked: " + unwrapPathError(err).Error()
+}
+
+// driftFindings returns the differences between e and its recorded identity.
+func driftFindings(e *entry, r snapshotEntry) []finding {
+	var found []finding
+	if e.Link != r.Link {
+		found = append(found, newFinding(ruleDriftLink,
+			fmt.Sprintf("target changed from %q to %q", r.Link, e.Link)))
+	}
+	switch mount, was := e.Dev != e.Pdev, r.Dev != r.Pdev; {
+	case mount && !was:
+		found = append(found, newFinding(ruleDriftMount, "now a mount point"))
+	case !mount && was:
+		found = append(found, newFinding(ruleDriftMount, "no longer a mount point"))
+	case e.Dev != r.Dev:
+		found = append(found, newFinding(ruleDriftDevice,
+			fmt.Sprintf("device changed from %d to %d", r.Dev, e.Dev)))
+	}
+	if e.Inode != r.Inode {
+		found = append(found, newFinding(ruleDriftInode,
+			fmt.Sprintf("inode changed from %d to %d", r.Inode, e.Inode)))
+	}
+	if e.Uid != r.Uid || e.Gid != r.Gid {
+		found = append(found, newFinding(ruleDriftOwner,
+			fmt.Sprintf("owner changed from %s to %s",
+				fmtOwner(r.User, r.Uid, r.Group, r.Gid), fmtOwner(e.User, e.Uid, e.Group, e.Gid))))
+	}
+	if e.Mode != r.Mode {
+		found = append(found, newFinding(ruleDriftMode,
+			fmt.Sprintf("mode changed from %s to %s", r.Mode, e.Mode)))
+	}
+	return found
+}
+
+// fmtOwner returns "user:group", using the numeric ID of either name that
+// could not be looked up.
+func fmtOwner(user string, uid int, group string, gid int) string {
+	if user == "" {
+		user = strconv.Itoa(uid)
+	}
+	if group == "" {
+		group = strconv.Itoa(gid)
+	}
+	return user + ":" + group
+}
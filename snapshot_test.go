@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestDriftEntries tests that differences from the baseline are attached to
+// the entries responsible for them.
+func TestDriftEntries(t *testing.T) {
+	base := []snapshotEntry{
+		{Dest: "/", Mode: "drwxr-xr-x", Dev: 1, Pdev: 1, Inode: 2, User: "root", Group: "root"},
+		{Dest: "/srv", Mode: "drwxr-xr-x", Dev: 1, Pdev: 1, Inode: 10, User: "root", Group: "root"},
+		{Dest: "/srv/current", Link: "v1", Mode: "lrwxrwxrwx", Dev: 1, Pdev: 1, Inode: 11, User: "root", Group: "root"},
+		{Dest: "/srv/v1", Mode: "drwxr-xr-x", Dev: 1, Pdev: 1, Inode: 12, User: "root", Group: "root", Level: 1},
+	}
+	toEntry := func(r snapshotEntry) entry {
+		return entry{Dest: r.Dest, Link: r.Link, Mode: r.Mode, Dev: r.Dev, Pdev: r.Pdev,
+			Inode: r.Inode, Uid: r.Uid, User: r.User, Gid: r.Gid, Group: r.Group, Level: r.Level}
+	}
+
+	tests := []struct {
+		name   string
+		change func(entries []entry) []entry
+		want   []string // rule of each finding, as "index:rule"
+	}{
+		{
+			name:   "unchanged",
+			change: func(entries []entry) []entry { return entries },
+		},
+		{
+			name: "retargeted link",
+			change: func(entries []entry) []entry {
+				entries[2].Link = "v2"
+				entries[3].Dest = "/srv/v2"
+				return entries
+			},
+			want: []string{"2:" + ruleDriftLink, "3:" + ruleDriftChain},
+		},
+		{
+			name: "replaced and chowned",
+			change: func(entries []entry) []entry {
+				entries[1].Inode = 99
+				entries[1].Uid, entries[1].User = 1000, "deploy"
+				return entries
+			},
+			want: []string{"1:" + ruleDriftInode, "1:" + ruleDriftOwner},
+		},
+		{
+			name: "new mount",
+			change: func(entries []entry) []entry {
+				entries[1].Dev, entries[1].Inode = 7, 2
+				return entries
+			},
+			want: []string{"1:" + ruleDriftMount, "1:" + ruleDriftInode},
+		},
+		{
+			name: "mode changed",
+			change: func(entries []entry) []entry {
+				entries[3].Mode = "drwxrwxrwx"
+				return entries
+			},
+			want: []string{"3:" + ruleDriftMode},
+		},
+		{
+			name: "chain shortened",
+			change: func(entries []entry) []entry {
+				entries[2].Link, entries[2].Mode = "", "drwxr-xr-x"
+				return entries[:3]
+			},
+			want: []string{"2:" + ruleDriftLink, "2:" + ruleDriftMode, "2:" + ruleDriftChain},
+		},
+		{
+			name: "chain lengthened",
+			change: func(entries []entry) []entry {
+				return append(entries, toEntry(snapshotEntry{Dest: "/srv/v1/next", Level: 1}))
+			},
+			want: []string{"4:" + ruleDriftChain},
+		},
+		{
+			name: "target removed",
+			change: func(entries []entry) []entry {
+				entries[3] = entry{Dest: "/srv/v1", Level: 1, Err: &os.PathError{Op: "lstat", Path: "/srv/v1", Err: os.ErrNotExist}}
+				return entries
+			},
+			want: []string{"3:" + ruleDriftChain},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entries []entry
+			for _, r := range base {
+				entries = append(entries, toEntry(r))
+			}
+			entries = tt.change(entries)
+			driftEntries(entries, base)
+
+			var got []string
+			for i, e := range entries {
+				for _, f := range e.Findings {
+					got = append(got, strconv.Itoa(i)+":"+f.Rule)
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("driftEntries() findings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadBaseline tests that malformed baselines are rejected.
+func TestLoadBaseline(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"valid", `{"version": 1, "paths": [{"path": "/etc", "entries": []}]}`, ""},
+		{"malformed", `{"version": `, "unexpected end of JSON input"},
+		{"unsupported version", `{"version": 2, "paths": [{"path": "/etc"}]}`, "unsupported version 2"},
+		{"no paths", `{"version": 1, "paths": []}`, "no paths recorded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "baseline.json")
+			if err := os.WriteFile(name, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			b, err := loadBaseline(name)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("loadBaseline() error = %v", err)
+				}
+				if got := b.paths(); len(got) != 1 || got[0] != "/etc" {
+					t.Errorf("loadBaseline() paths = %v, want [/etc]", got)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadBaseline() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestPrintSnapshot tests that snapshots record absolute paths.
+func TestPrintSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile("file", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := printSnapshot(context.Background(), &out, []string{"file"}, options{}); err != nil {
+		t.Fatalf("printSnapshot() error = %v", err)
+	}
+	name := filepath.Join(dir, "baseline.json")
+	if err := os.WriteFile(name, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := loadBaseline(name)
+	if err != nil {
+		t.Fatalf("loadBaseline() error = %v", err)
+	}
+	if got, want := b.paths(), filepath.Join(dir, "file"); len(got) != 1 || got[0] != want {
+		t.Errorf("printSnapshot() paths = %v, want [%s]", got, want)
+	}
+}
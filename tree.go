@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Glyphs drawing the branches of --tree, as by tree(1).
+const (
+	treeBranch     = "├── "
+	treeLastBranch = "└── "
+	treeIndent     = "│   "
+	treeLastIndent = "    "
+)
+
+// treeNode is a file reached by any of the chains merged into a tree, with
+// the files reached beneath it.
+type treeNode struct {
+	entry    entry
+	name     string
+	children []*treeNode
+}
+
+// buildTree merges chains into a forest of the files they reach, placed by
+// their physical path, so that each file appears once however many chains
+// reach it. A link thus appears in its directory with its target, and the
+// target in its own directory. Files whose directory was never reached are
+// the roots, named by their full path, in the order first reached, as are
+// the children of each node.
+func buildTree(chains [][]entry) []*treeNode {
+	var roots []*treeNode
+	nodes := map[string]*treeNode{}
+	for _, entries := range chains {
+		for _, e := range entries {
+			// Parent references reach a directory already in the tree.
+			if e.Name == ".." {
+				continue
+			}
+			// Components after a link are reached through it, so resolve
+			// the directory but not the file itself, which may be a link.
+			dest := filepath.Clean(e.Dest)
+			dir := physicalPath(filepath.Dir(dest))
+			key := filepath.Join(dir, filepath.Base(dest))
+			if dest == filepath.Dir(dest) {
+				key = dest
+			}
+			if _, ok := nodes[key]; ok {
+				continue
+			}
+			n := &treeNode{entry: e, name: e.Name}
+			nodes[key] = n
+			if parent := nodes[dir]; parent != nil && dir != key {
+				parent.children = append(parent.children, n)
+			} else {
+				n.name = key
+				roots = append(roots, n)
+			}
+		}
+	}
+	return roots
+}
+
+// printTree resolves each path and prints the merged tree of their chains,
+// with the metadata columns aligned across the whole tree. A walk that failed
+// is merged up to the entry in error, and the first such error returned.
+func printTree(ctx context.Context, out io.Writer, paths []string, opts options, resolve resolveFunc) error {
+	var (
+		chains  [][]entry
+		all     []entry
+		walkErr error
+	)
+	for _, p := range paths {
+		entries, err := resolve(ctx, p, opts)
+		if err != nil {
+			if len(entries) == 0 {
+				return err
+			}
+			if walkErr == nil {
+				walkErr = err
+			}
+		}
+		chains = append(chains, entries)
+		all = append(all, entries...)
+	}
+	printNodes(out, buildTree(chains), "", true, opts, calculateWidths(all, opts))
+	return walkErr
+}
+
+// printNodes prints each node after prefix and a branch glyph (none for the
+// roots), followed by its children.
+func printNodes(w io.Writer, nodes []*treeNode, prefix string, roots bool, opts options, widths widths) {
+	for i, n := range nodes {
+		branch, indent := treeBranch, treeIndent
+		if i == len(nodes)-1 {
+			branch, indent = treeLastBranch, treeLastIndent
+		}
+		if roots {
+			branch, indent = "", ""
+		}
+
+		e := n.entry
+		if e.Err != nil {
+			e.Name = n.name
+			fmt.Fprint(w, strings.TrimSuffix(prefix+branch, " "))
+			printError(w, e)
+		} else {
+			name := n.name
+			if !opts.noFollow {
+				name += e.fmtLink()
+			}
+			e.printAs(w, prefix+branch+name, opts, widths)
+		}
+		printNodes(w, n.children, prefix+indent, false, opts, widths)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestPrintTree tests that the chains of several paths are merged so that
+// shared directories and link targets are printed once.
+func TestPrintTree(t *testing.T) {
+	base := physicalPath(t.TempDir())
+	for _, dir := range []string{"usr/bin", "usr/lib/python3.11"} {
+		if err := os.MkdirAll(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range map[string]string{
+		"usr/bin/python3": "python",
+		"usr/bin/python":  "../lib/python3.11",
+		"lib":             filepath.Join(base, "usr", "lib"),
+	} {
+		if err := os.Symlink(target, filepath.Join(base, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths := []string{
+		filepath.Join(base, "usr", "bin", "python3"),
+		filepath.Join(base, "usr", "bin", "python"),
+		filepath.Join(base, "lib", "python3.11"),
+	}
+	var out bytes.Buffer
+	if err := printTree(context.Background(), &out, paths, options{}, resolvePath); err != nil {
+		t.Fatalf("printTree() error = %v", err)
+	}
+
+	// Only the lines beneath the temporary directory are compared, without
+	// the glyphs of its ancestors.
+	lines := strings.Split(out.String(), "\n")
+	i := slices.IndexFunc(lines, func(l string) bool { return strings.HasSuffix(l, " "+filepath.Base(base)) })
+	if i < 0 || i+1 >= len(lines) {
+		t.Fatalf("printTree() output = %q, want %s", out.String(), base)
+	}
+	prefix, _, _ := strings.Cut(lines[i+1], "├")
+	for j := range lines[i+1:] {
+		lines[i+1+j] = strings.TrimPrefix(lines[i+1+j], prefix)
+	}
+	got := strings.Join(lines[i+1:], "\n")
+	want := strings.Join([]string{
+		"├── usr",
+		"│   ├── bin",
+		"│   │   ├── python3 -> python",
+		"│   │   └── python -> ../lib/python3.11",
+		"│   └── lib",
+		"│       └── python3.11",
+		"└── lib -> " + filepath.Join(base, "usr", "lib"),
+		"",
+	}, "\n")
+	if got != want {
+		t.Errorf("printTree() output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestBuildTree tests that files whose directory was never reached become
+// roots named by their full path.
+func TestBuildTree(t *testing.T) {
+	chains := [][]entry{
+		{{Dest: "/", Name: "/"}, {Dest: "/usr", Name: "usr"}},
+		{{Dest: "/usr", Name: "usr"}, {Dest: "/usr/bin", Name: "bin"}},
+		{{Dest: "/nonexistent/dir", Name: "dir"}},
+	}
+	roots := buildTree(chains)
+	if len(roots) != 2 || roots[0].name != "/" || roots[1].name != "/nonexistent/dir" {
+		t.Fatalf("buildTree() roots = %+v, want / and /nonexistent/dir", roots)
+	}
+	usr := roots[0].children
+	if len(usr) != 1 || len(usr[0].children) != 1 || usr[0].children[0].name != "bin" {
+		t.Errorf("buildTree() did not merge /usr/bin beneath /usr")
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// watchTimeFormat is the layout of the timestamps printed by --watch.
+const watchTimeFormat = "2006-01-02 15:04:05.000000"
+
+// watchPaths prints the resolution chain of each path, then waits for any
+// entry of any chain to change. Each time a chain resolves differently, it
+// prints a diff of the chain under a timestamp, and runs the --on-change
+// command if the target or a link changed. Watching ends without error when
+// ctx is done.
+func watchPaths(ctx context.Context, out, errOut io.Writer, paths []string, opts options) error {
+	chains := make([][]entry, len(paths))
+	for first := true; ; first = false {
+		var names []string
+		for i, p := range paths {
+			entries := watchChain(ctx, p, opts)
+			if ctx.Err() != nil {
+				return nil
+			}
+			for _, e := range entries {
+				names = append(names, e.Dest)
+			}
+
+			switch {
+			case first:
+				if i > 0 {
+					fmt.Fprintln(out)
+				}
+				fmt.Fprintf(out, "-- %s %s\n", time.Now().Format(watchTimeFormat), p)
+				printEntries(out, entries, opts, calculateWidths(entries, opts))
+			case !sameChain(chains[i], entries):
+				fmt.Fprintln(out)
+				fmt.Fprintf(out, "-- %s %s\n", time.Now().Format(watchTimeFormat), p)
+				printChainDiff(out, chains[i], slices.Clone(entries), opts)
+				if opts.onChange != "" && retargeted(chains[i], entries) {
+					runOnChange(ctx, out, errOut, opts.onChange, p, chains[i], entries)
+				}
+			}
+			chains[i] = entries
+		}
+
+		changed := func() bool {
+			for i, p := range paths {
+				if !sameChain(chains[i], watchChain(ctx, p, opts)) {
+					return true
+				}
+			}
+			return false
+		}
+		if err := waitChange(ctx, names, changed); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// watchChain walks path and returns its entries. A walk ending at an entry
+// that cannot be resolved is reported rather than treated as an error, since
+// the entry may yet appear.
+func watchChain(ctx context.Context, path string, opts options) []entry {
+	entries, _ := collectEntries(ctx, path, opts)
+	return entries
+}
+
+// sameChain reports whether two walks resolved through identical entries.
+func sameChain(a, b []entry) bool {
+	return slices.EqualFunc(a, b, sameEntry)
+}
+
+// sameEntry reports whether two entries have the same identity, and failed
+// to resolve for the same reason if at all.
+func sameEntry(a, b entry) bool {
+	return makeSnapshotEntry(a) == makeSnapshotEntry(b) && errorString(a.Err) == errorString(b.Err)
+}
+
+// errorString returns the message of err, or "" if err is nil.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// printChainDiff prints the entries of the chain cur, prefixed with " " if
+// unchanged from the previous walk old, and the changed entries of both,
+// prefixed with "-" and "+". Entries are compared position by position until
+// the chains diverge; the remainder of each is then removed and added. An
+// entry changed in place is annotated with how it differs.
+func printChainDiff(out io.Writer, old, cur []entry, opts options) {
+	w := calculateWidths(slices.Concat(old, cur), opts)
+
+	i := 0
+	for ; i < len(old) && i < len(cur); i++ {
+		if old[i].Dest != cur[i].Dest || old[i].Level != cur[i].Level {
+			break
+		}
+		if sameEntry(old[i], cur[i]) {
+			printDiffLine(out, " ", cur[i], opts, w)
+			continue
+		}
+		if old[i].Err == nil && cur[i].Err == nil {
+			cur[i].Findings = append(cur[i].Findings, driftFindings(&cur[i], makeSnapshotEntry(old[i]))...)
+		}
+		printDiffLine(out, "-", old[i], opts, w)
+		printDiffLine(out, "+", cur[i], opts, w)
+	}
+	for _, e := range old[i:] {
+		printDiffLine(out, "-", e, opts, w)
+	}
+	for _, e := range cur[i:] {
+		printDiffLine(out, "+", e, opts, w)
+	}
+}
+
+// printDiffLine prints an entry as printEntries does, after the given mark.
+func printDiffLine(out io.Writer, mark string, e entry, opts options, w widths) {
+	var b strings.Builder
+	printEntries(&b, []entry{e}, opts, w)
+	fmt.Fprint(out, mark, " ", b.String())
+}
+
+// chainTarget returns the canonical path and (dev, inode) of the final
+// target of a walk, or "" if it could not be resolved. The path of the final
+// entry itself may lead through links that were retargeted.
+func chainTarget(entries []entry) (path string, dev, inode uint64) {
+	if len(entries) == 0 {
+		return "", 0, 0
+	}
+	e := entries[len(entries)-1]
+	if e.Err != nil {
+		return "", 0, 0
+	}
+	canon := canonicalPaths(entries)
+	return canon[len(canon)-1], e.Dev, e.Inode
+}
+
+// retargeted reports whether the final target of a walk is a different file
+// than in the previous walk old, or any link of the chain was retargeted.
+func retargeted(old, cur []entry) bool {
+	_, oldDev, oldInode := chainTarget(old)
+	_, dev, inode := chainTarget(cur)
+	if dev != oldDev || inode != oldInode {
+		return true
+	}
+	links := func(entries []entry) (link [][2]string) {
+		for _, e := range entries {
+			if e.Link != "" {
+				link = append(link, [2]string{e.Dest, e.Link})
+			}
+		}
+		return link
+	}
+	return !slices.Equal(links(old), links(cur))
+}
+
+// runOnChange runs hook with the shell, passing the watched path and its
+// previous and new targets in the environment. A hook that fails is reported
+// without ending the watch.
+func runOnChange(ctx context.Context, out, errOut io.Writer, hook, path string, old, cur []entry) {
+	oldTarget, _, _ := chainTarget(old)
+	newTarget, _, _ := chainTarget(cur)
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", hook)
+	cmd.Env = append(os.Environ(),
+		"LSI_PATH="+path,
+		"LSI_OLD_TARGET="+oldTarget,
+		"LSI_NEW_TARGET="+newTarget,
+	)
+	cmd.Stdout, cmd.Stderr = out, errOut
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(errOut, "%s: on-change: %v\n", command, err)
+	}
+}
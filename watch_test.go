@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestPrintChainDiff tests that changed entries are removed and added, and
+// that entries changed in place are annotated.
+func TestPrintChainDiff(t *testing.T) {
+	old := []entry{
+		{Name: "srv", Dest: "/srv", Inode: 10},
+		{Name: "current", Dest: "/srv/current", Link: "v1", Inode: 11},
+		{Name: "v1", Dest: "/srv/v1", Inode: 12, Level: 1},
+	}
+	cur := []entry{
+		{Name: "srv", Dest: "/srv", Inode: 10},
+		{Name: "current", Dest: "/srv/current", Link: "v2", Inode: 13},
+		{Name: "v2", Dest: "/srv/v2", Inode: 14, Level: 1},
+	}
+
+	var out bytes.Buffer
+	printChainDiff(&out, old, cur, options{})
+	want := strings.Join([]string{
+		"  srv",
+		"- current -> v1",
+		`+ current -> v2 [drift-link: target changed from "v1" to "v2"] [drift-inode: inode changed from 11 to 13]`,
+		"-   v1",
+		"+   v2",
+		"",
+	}, "\n")
+	if out.String() != want {
+		t.Errorf("printChainDiff() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+// TestSameChain tests the comparison of walks.
+func TestSameChain(t *testing.T) {
+	a := []entry{{Dest: "/srv", Inode: 10}, {Dest: "/srv/app", Inode: 11}}
+	tests := []struct {
+		name string
+		b    []entry
+		want bool
+	}{
+		{"identical", []entry{{Dest: "/srv", Inode: 10}, {Dest: "/srv/app", Inode: 11}}, true},
+		{"replaced", []entry{{Dest: "/srv", Inode: 10}, {Dest: "/srv/app", Inode: 12}}, false},
+		{"shorter", []entry{{Dest: "/srv", Inode: 10}}, false},
+		{"failed", []entry{{Dest: "/srv", Inode: 10}, {Dest: "/srv/app", Inode: 11, Err: os.ErrNotExist}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameChain(a, tt.b); got != tt.want {
+				t.Errorf("sameChain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRetargeted tests which changes run the --on-change command.
+func TestRetargeted(t *testing.T) {
+	old := []entry{
+		{Dest: "/srv/current", Link: "v1", Inode: 11},
+		{Dest: "/srv/v1", Dev: 1, Inode: 12, Mode: "drwxr-xr-x", Level: 1},
+	}
+	tests := []struct {
+		name string
+		cur  []entry
+		want bool
+	}{
+		{"unchanged", []entry{old[0], old[1]}, false},
+		{"chmod", []entry{old[0], {Dest: "/srv/v1", Dev: 1, Inode: 12, Mode: "drwx------", Level: 1}}, false},
+		{"replaced target", []entry{old[0], {Dest: "/srv/v1", Dev: 1, Inode: 13, Level: 1}}, true},
+		{"retargeted link", []entry{{Dest: "/srv/current", Link: "./v1", Inode: 11}, old[1]}, true},
+		{"missing target", []entry{old[0], {Dest: "/srv/v1", Level: 1, Err: os.ErrNotExist}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retargeted(old, tt.cur); got != tt.want {
+				t.Errorf("retargeted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRunOnChange tests that the command is given the old and new targets,
+// resolved through a link retargeted before the final component.
+func TestRunOnChange(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("on-change commands run with /bin/sh")
+	}
+
+	dir := physicalPath(t.TempDir())
+	for _, name := range []string{"v1", "v2"} {
+		if err := os.MkdirAll(filepath.Join(dir, "releases", name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "releases", name, "config"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(dir, "current")
+	if err := os.Symlink(filepath.Join("releases", "v1"), link); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(link, "config")
+	old := watchChain(context.Background(), path, options{})
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("releases", "v2"), link); err != nil {
+		t.Fatal(err)
+	}
+	cur := watchChain(context.Background(), path, options{})
+
+	hook := `printf '%s %s %s' "$LSI_PATH" "$LSI_OLD_TARGET" "$LSI_NEW_TARGET"; exit 3`
+	var out, errOut bytes.Buffer
+	runOnChange(context.Background(), &out, &errOut, hook, path, old, cur)
+	want := path + " " + filepath.Join(dir, "releases", "v1", "config") + " " + filepath.Join(dir, "releases", "v2", "config")
+	if out.String() != want {
+		t.Errorf("runOnChange() output = %q, want %q", out.String(), want)
+	}
+	if want := "lsi: on-change: exit status 3\n"; errOut.String() != want {
+		t.Errorf("runOnChange() error output = %q, want %q", errOut.String(), want)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestWatchPaths tests that retargeting a watched symlink prints a diff.
+func TestWatchPaths(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("watching requires inotify")
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"v1", "v2"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(dir, "current")
+	if err := os.Symlink("v1", link); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var out lockedBuffer
+	done := make(chan error)
+	go func() { done <- watchPaths(ctx, &out, io.Discard, []string{link}, options{}) }()
+
+	// waitFor polls the output until it contains s.
+	waitFor := func(s string) {
+		t.Helper()
+		for !strings.Contains(out.String(), s) {
+			if ctx.Err() != nil {
+				t.Fatalf("watchPaths() output = %q, want to contain %q", out.String(), s)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitFor("current -> v1\n")
+	tmp := filepath.Join(dir, "next")
+	if err := os.Symlink("v2", tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(`+ current -> v2 [drift-link: target changed from "v1" to "v2"]`)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("watchPaths() error = %v, want nil once canceled", err)
+	}
+}